@@ -11,12 +11,27 @@ type Column struct {
 	Name   string
 }
 
+// BlockKind is the kind of the block that the server sends
+type BlockKind int
+
+const (
+	// BlockData is a block of the result of the query
+	BlockData BlockKind = iota
+	// BlockTotals is the totals of the query (e.g. GROUP BY ... WITH TOTALS)
+	BlockTotals
+	// BlockExtremes is the minimum and maximum values of the result (extremes setting)
+	BlockExtremes
+)
+
 type Block struct {
 	Columns      []*Column
 	NumRows      uint64
 	NumColumns   uint64
+	Kind         BlockKind
 	info         blockInfo
 	headerWriter *readerwriter.Writer
+	// compress is true if the block data is compressed
+	compress bool
 }
 
 func newBlock() *Block {
@@ -25,12 +40,34 @@ func newBlock() *Block {
 	}
 }
 
+// IsOverflows is true if the block is the rows that exceeded max_rows_to_group_by in the totals
+// (group_by_overflow_mode = 'any')
+func (block *Block) IsOverflows() bool {
+	return block.info.isOverflows
+}
+
+// BucketNum is the bucket number of the two-level aggregation or -1 if the block is not a bucket
+func (block *Block) BucketNum() int32 {
+	return block.info.bucketNum
+}
+
 func (block *Block) read(ch *conn) error {
+	block.compress = ch.compress
+	return block.readInfo(ch)
+}
+
+// readUncompressed reads the blocks that the server always sends without compression (e.g. profile events).
+func (block *Block) readUncompressed(ch *conn) error {
+	block.compress = false
+	return block.readInfo(ch)
+}
+
+func (block *Block) readInfo(ch *conn) error {
 	if _, err := ch.reader.String(); err != nil { // temporary table
 		return err
 	}
 
-	ch.reader.SetCompress(ch.compress)
+	ch.reader.SetCompress(block.compress)
 	defer ch.reader.SetCompress(false)
 	var err error
 	err = block.info.read(ch.reader)
@@ -87,9 +124,39 @@ func (block *Block) nextColumn(ch *conn) (*Column, error) {
 	if col.ChType, err = ch.reader.String(); err != nil {
 		return col, &readError{"block: read column type", err}
 	}
+	if ch.serverInfo.Revision >= dbmsMinRevisionWithCustomSerialization {
+		hasCustom, err := ch.reader.Bool()
+		if err != nil {
+			return col, &readError{"block: read custom serialization", err}
+		}
+		if hasCustom {
+			return col, &customSerializationError{column: col.Name}
+		}
+	}
 	return col, nil
 }
 
+// skipColumns reads and discards all the columns of the block.
+func (block *Block) skipColumns(ch *conn) error {
+	return block.skipColumnsFrom(ch, 0)
+}
+
+// skipColumnsFrom reads and discards the columns of the block after the first n columns that are already read.
+func (block *Block) skipColumnsFrom(ch *conn, n uint64) error {
+	ch.reader.SetCompress(block.compress)
+	defer ch.reader.SetCompress(false)
+	for i := n; i < block.NumColumns; i++ {
+		col, err := block.nextColumn(ch)
+		if err != nil {
+			return err
+		}
+		if err := skipColumn(ch.reader, col.ChType, int(block.NumRows)); err != nil {
+			return &readError{"block: skip column " + col.Name, err}
+		}
+	}
+	return nil
+}
+
 func (block *Block) writeHeader(ch *conn, numRows int) error {
 	block.info.write(ch.writer)
 	// NumColumns
@@ -127,6 +194,10 @@ func (block *Block) writeColumsBuffer(ch *conn, columns ...column.Column) error
 		block.headerWriter.Reset()
 		block.headerWriter.String(column.Name)
 		block.headerWriter.String(column.ChType)
+		if ch.serverInfo.Revision >= dbmsMinRevisionWithCustomSerialization {
+			// has custom serialization
+			block.headerWriter.Bool(false)
+		}
 
 		columns[i].HeaderWriter(block.headerWriter)
 		if _, err := block.headerWriter.WriteTo(ch.writertoCompress); err != nil {
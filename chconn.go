@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/vahid-sohrabloo/chconn/internal/ctxwatch"
 	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
 	"github.com/vahid-sohrabloo/chconn/setting"
@@ -30,8 +33,14 @@ const (
 	clientQuery = 1
 	// A block of data (compressed or not).
 	clientData = 2
+	// Cancel the query execution.
+	clientCancel = 3
 	// Check that connection to the server is alive.
 	clientPing = 4
+	// Check status of tables on the server.
+	clientTablesStatusRequest = 5
+	// Reply to the ReadTaskRequest of the server.
+	clientReadTaskResponse = 9
 )
 
 const (
@@ -53,38 +62,89 @@ const (
 	serverTotals = 7
 	// A block with minimums and maximums (compressed or not).
 	serverExtremes = 8
+	// A response to TablesStatus request.
+	serverTablesStatusResponse = 9
 
+	// System logs of the query execution
+	serverLog = 10
 	// Columns' description for default values calculation
 	serverTableColumns = 11
+	// List of unique parts ids.
+	serverPartUUIDs = 12
+	// String (UUID) describes a request for which next task is needed
+	serverReadTaskRequest = 13
+	// Packet with profile events from server.
+	serverProfileEvents = 14
+	// The session timezone of the query was changed.
+	serverTimezoneUpdate = 17
 )
 
 const (
-	dbmsMinRevisionWithClientInfo                  = 54032
-	dbmsMinRevisionWithServerTimezone              = 54058
-	dbmsMinRevisionWithQuotaKeyInClientInfo        = 54060
-	dbmsMinRevisionWithServerDisplayName           = 54372
-	dbmsMinRevisionWithVersionPatch                = 54401
-	dbmsMinRevisionWithClientWriteInfo             = 54420
-	dbmsMinRevisionWithSettingsSerializedAsStrings = 54429
-	dbmsMinRevisionWithInterserverSecret           = 54441
-	dbmsMinRevisionWithOpentelemetry               = 54442
+	dbmsMinRevisionWithClientInfo                       = 54032
+	dbmsMinRevisionWithTablesStatus                     = 54226
+	dbmsMinRevisionWithServerTimezone                   = 54058
+	dbmsMinRevisionWithQuotaKeyInClientInfo             = 54060
+	dbmsMinRevisionWithServerDisplayName                = 54372
+	dbmsMinRevisionWithVersionPatch                     = 54401
+	dbmsMinRevisionWithClientWriteInfo                  = 54420
+	dbmsMinRevisionWithSettingsSerializedAsStrings      = 54429
+	dbmsMinRevisionWithInterserverSecret                = 54441
+	dbmsMinRevisionWithOpentelemetry                    = 54442
+	dbmsMinProtocolVersionWithDistributedDepth          = 54448
+	dbmsMinProtocolVersionWithInitialQueryStartTime     = 54449
+	dbmsMinProtocolVersionWithIncrementalProfileEvents  = 54451
+	dbmsMinRevisionWithParallelReplicas                 = 54453
+	dbmsMinRevisionWithCustomSerialization              = 54454
+	dbmsMinProtocolVersionWithAddendum                  = 54458
+	dbmsMinProtocolVersionWithParameters                = 54459
+	dbmsMinProtocolVersionWithServerQueryTimeInProgress = 54460
+	dbmsMinProtocolVersionWithPasswordComplexityRules   = 54461
+	dbmsMinRevisionWithInterserverSecretV2              = 54462
+	dbmsMinProtocolVersionWithTotalBytesInProgress      = 54463
+	dbmsMinProtocolVersionWithTimezoneUpdates           = 54464
 )
 
+// dbmsClusterProcessingProtocolVersion is the version of the ReadTaskResponse packet
+const dbmsClusterProcessingProtocolVersion = 1
+
 const (
 	dbmsVersionMajor    = 1
 	dbmsVersionMinor    = 0
 	dbmsVersionPatch    = 0
-	dbmsVersionRevision = 54442
+	dbmsVersionRevision = 54464
 )
 
-type queryProcessingStage uint64
+// QueryProcessingStage is the stage up to which the server processes the query. The stages before Complete are used to
+// query the shards directly and merge the results on the client (e.g. like a Distributed table)
+type QueryProcessingStage uint8
 
 const (
-
-	// queryProcessingStageComplete Completely.
-	queryProcessingStageComplete queryProcessingStage = 2
+	// QueryProcessingStageComplete processes the query completely. It is the default stage
+	QueryProcessingStageComplete QueryProcessingStage = iota
+	// QueryProcessingStageFetchColumns only reads the columns that are needed by the query
+	QueryProcessingStageFetchColumns
+	// QueryProcessingStageWithMergeableState processes the query until the intermediate states of the aggregate
+	// functions, so the blocks of the shards can be merged
+	QueryProcessingStageWithMergeableState
+	// QueryProcessingStageWithMergeableStateAfterAggregation processes the query until after the aggregation
+	// (e.g. with distributed_group_by_no_merge=2), so the results of the shards only need ORDER BY and LIMIT
+	QueryProcessingStageWithMergeableStateAfterAggregation
 )
 
+// protocolValue is the value of the stage in the native protocol.
+func (s QueryProcessingStage) protocolValue() uint64 {
+	switch s {
+	case QueryProcessingStageFetchColumns:
+		return 0
+	case QueryProcessingStageWithMergeableState:
+		return 1
+	case QueryProcessingStageWithMergeableStateAfterAggregation:
+		return 3
+	default:
+		return 2
+	}
+}
+
 // DialFunc is a function that can be used to connect to a ClickHouse server.
 type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
 
@@ -98,6 +158,43 @@ type ReaderFunc func(io.Reader) io.Reader
 // Note: DO NOT use bufio.Writer, chconn doesn't support flush
 type WriterFunc func(io.Writer) io.Writer
 
+// QueryOptions contains the options of a query. A nil QueryOptions is the same as an empty one.
+type QueryOptions struct {
+	QueryID    string
+	Settings   *setting.Settings
+	OnProgress func(*Progress)
+	OnProfile  func(*Profile)
+	// OnLog is called for the logs of the query. The server only sends the logs if the send_logs_level setting is set
+	OnLog func(*ServerLog)
+	// OnProfileEvents is called for each ProfileEvents packet of the query (e.g. SelectedRows or ReadCompressedBytes).
+	// ProfileEvents.Add can be used to aggregate them
+	OnProfileEvents func([]ProfileEvent)
+	// ExternalTables are sent with the query and can be used in the query like temporary tables
+	ExternalTables []*ExternalTable
+	// TraceContext is the OpenTelemetry trace context of the query. The server links the spans of the query in
+	// system.opentelemetry_span_log to it
+	TraceContext *TraceContext
+	// Parameters are the values of the query parameters (e.g. {name:String}).
+	// The values are in the same format as --param_<name> of clickhouse-client
+	Parameters map[string]string
+	// InitialUser and InitialQueryID are the user and the query id of the query that initiated this query
+	// (e.g. the query of a Distributed table). The server runs the query on behalf of InitialUser if the connection
+	// uses the interserver secret (Config.ClusterSecret)
+	InitialUser    string
+	InitialQueryID string
+	// QuotaKey is the key of the quota of the query. It overrides Config.QuotaKey
+	QuotaKey string
+	// ClientName, OSUser and ClientHostname override the client info of the query
+	// (e.g. the client_name, os_user and client_hostname columns of system.query_log)
+	ClientName     string
+	OSUser         string
+	ClientHostname string
+	// Stage is the stage up to which the server processes the query. The blocks of the result are in the state of
+	// the stage (e.g. the columns of the aggregate functions are AggregateFunction(...) in WithMergeableState).
+	// It must be Complete for insert queries
+	Stage QueryProcessingStage
+}
+
 // Conn is a low-level Clickhouse connection handle. It is not safe for concurrent usage.
 type Conn interface {
 	// RawConn Get Raw Connection. Do not use unless you know what you want to do
@@ -112,12 +209,16 @@ type Conn interface {
 	ServerInfo() ServerInfo
 	// Ping sends a ping to check that the connection to the server is alive.
 	Ping(ctx context.Context) error
-	// Exec executes a query without returning any rows.
+	// TablesStatus get the replication status of the tables (e.g. the delay of the replica).
+	// The tables that do not exist on the server are not in the result
+	TablesStatus(ctx context.Context, tables []TableName) (map[TableName]TableStatus, error)
+	// Exec executes a query without returning any rows. It reads the response until the end of the query and returns
+	// the progress of the query.
 	// NOTE: don't use it for insert and select query
-	Exec(ctx context.Context, query string) (interface{}, error)
+	Exec(ctx context.Context, query string) (*ExecResult, error)
 	// ExecWithSetting executes a query without returning any rows with the setting option.
 	// NOTE: don't use it for insert and select query
-	ExecWithSetting(ctx context.Context, query string, settings *setting.Settings) (interface{}, error)
+	ExecWithSetting(ctx context.Context, query string, settings *setting.Settings) (*ExecResult, error)
 	// ExecCallback executes a query without returning any rows with the setting option and on progress callback.
 	// NOTE: don't use it for insert and select query
 	ExecCallback(
@@ -126,13 +227,31 @@ type Conn interface {
 		settings *setting.Settings,
 		queryID string,
 		onProgress func(*Progress),
-	) (interface{}, error)
+	) (*ExecResult, error)
+	// ExecWithOption executes a query without returning any rows with the query options.
+	// The other Exec methods are shortcuts of it with some of the options.
+	// NOTE: don't use it for insert and select query
+	ExecWithOption(ctx context.Context, query string, queryOptions *QueryOptions) (*ExecResult, error)
 	// Insert executes a query and return insert stmt.
 	// NOTE: only use for insert query
 	Insert(ctx context.Context, query string) (InsertStmt, error)
 	// InsertWithSetting executes a query with the setting option and return insert stmt.
 	// NOTE: only use for insert query
 	InsertWithSetting(ctx context.Context, query string, settings *setting.Settings, queryID string) (InsertStmt, error)
+	// InsertWithOption executes a query with the query options and return insert stmt.
+	// The other Insert methods are shortcuts of it with some of the options.
+	// NOTE: only use for insert query
+	InsertWithOption(ctx context.Context, query string, queryOptions *QueryOptions) (InsertStmt, error)
+	// InsertOmitDefaults prepares an insert into the table without the columns that have a default value and return
+	// insert stmt. columns are the columns with a default value that are inserted too. The database of the table can be
+	// empty for the default database.
+	// The columns of the insert are available in GetBlock().Columns and the server fills the omitted columns.
+	InsertOmitDefaults(
+		ctx context.Context,
+		table TableName,
+		columns []string,
+		queryOptions *QueryOptions,
+	) (InsertStmt, error)
 	// Select executes a query and return select stmt.
 	// NOTE: only use for select query
 	Select(ctx context.Context, query string) (SelectStmt, error)
@@ -148,6 +267,10 @@ type Conn interface {
 		queryID string,
 		onProgress func(*Progress),
 		onProfile func(*Profile)) (SelectStmt, error)
+	// SelectWithOption executes a query with the query options and return select stmt.
+	// The other Select methods are shortcuts of it with some of the options.
+	// NOTE: only use for select query
+	SelectWithOption(ctx context.Context, query string, queryOptions *QueryOptions) (SelectStmt, error)
 }
 
 type writeFlusher interface {
@@ -160,6 +283,8 @@ type conn struct {
 	parameterStatuses map[string]string // parameters that have been reported by the server
 	serverInfo        ServerInfo
 	clientInfo        *ClientInfo
+	// interserverSalt is the salt of the hash of the queries if the connection uses the interserver secret
+	interserverSalt string
 
 	config *Config
 
@@ -171,8 +296,29 @@ type conn struct {
 
 	reader   *readerwriter.Reader
 	compress bool
+	// readCounter counts the bytes that are read by reader
+	readCounter *readCounter
+
+	// deadlineMu guards fixedDeadline. fixedDeadline is true when the deadline of the connection is set to interrupt
+	// it (e.g. for the cancellation), so ReadTimeout and WriteTimeout don't change the deadline
+	deadlineMu    sync.Mutex
+	fixedDeadline bool
 
 	contextWatcher *ctxwatch.ContextWatcher
+
+	// writeMu serializes the writes to the connection with the cancel packet that is sent by the context watcher.
+	writeMu sync.Mutex
+	// inFlight is true when a query was sent and the server didn't end it yet. guarded by writeMu
+	inFlight bool
+	// canceled is true when the context of the current query was canceled. guarded by writeMu
+	canceled bool
+
+	// onLog is the OnLog callback of the current query
+	onLog func(*ServerLog)
+	// onProfileEvents is the OnProfileEvents callback of the current query
+	onProfileEvents func([]ProfileEvent)
+	// tableColumns is the description of the table columns of the current insert query
+	tableColumns []TableColumn
 }
 
 // Connect establishes a connection to a ClickHouse server using the environment and connString (in URL or DSN format)
@@ -284,6 +430,11 @@ func connect(ctx context.Context, config *Config, fallbackConfig *FallbackConfig
 		return nil, &connectError{config: config, msg: "dial error", err: err}
 	}
 
+	if err := setTCPOptions(c.conn, config); err != nil {
+		c.conn.Close()
+		return nil, &connectError{config: config, msg: "set tcp options", err: err}
+	}
+
 	c.parameterStatuses = make(map[string]string)
 
 	if fallbackConfig.TLSConfig != nil {
@@ -291,30 +442,47 @@ func connect(ctx context.Context, config *Config, fallbackConfig *FallbackConfig
 	}
 
 	c.status = connStatusConnecting
-	c.contextWatcher = ctxwatch.NewContextWatcher(
+	// there is no query to cancel in the handshake, so the connection is just interrupted
+	connectWatcher := ctxwatch.NewContextWatcher(
 		func() {
-			c.conn.SetDeadline(time.Date(1, 1, 1, 1, 1, 1, 1, time.UTC)) //nolint:errcheck //no need
+			c.setFixedDeadline(time.Date(1, 1, 1, 1, 1, 1, 1, time.UTC))
 		},
 		func() {
-			c.conn.SetDeadline(time.Time{}) //nolint:errcheck //no need
+			c.setFixedDeadline(time.Time{})
+		},
+	)
+	c.contextWatcher = ctxwatch.NewContextWatcher(
+		c.cancel,
+		func() {
+			c.setFixedDeadline(time.Time{})
 		},
 	)
 
-	c.contextWatcher.Watch(ctx)
-	defer c.contextWatcher.Unwatch()
+	connectWatcher.Watch(ctx)
+	defer connectWatcher.Unwatch()
 	c.writer = readerwriter.NewWriter()
+	var connReader io.Reader = c.conn
+	if config.ReadTimeout > 0 {
+		connReader = &timeoutReader{ch: c, r: c.conn}
+	}
+	c.readCounter = &readCounter{r: connReader}
 	if config.ReaderFunc != nil {
-		c.reader = readerwriter.NewReader(config.ReaderFunc(c.conn))
+		c.reader = readerwriter.NewReader(config.ReaderFunc(c.readCounter))
 	} else {
-		c.reader = readerwriter.NewReader(bufio.NewReaderSize(c.conn, 4096))
+		c.reader = readerwriter.NewReader(bufio.NewReaderSize(c.readCounter, 4096))
+	}
+	c.reader.SetChecksum(!config.SkipCompressChecksum)
+	var connWriter io.Writer = c.conn
+	if config.WriteTimeout > 0 {
+		connWriter = &timeoutWriter{ch: c, w: c.conn}
 	}
 	if config.WriterFunc != nil {
-		c.writerto = config.WriterFunc(c.conn)
+		c.writerto = config.WriterFunc(connWriter)
 	} else {
-		c.writerto = c.conn
+		c.writerto = connWriter
 	}
 	if c.compress {
-		c.writertoCompress = readerwriter.NewCompressWriter(c.writerto)
+		c.writertoCompress = newCompressWriter(c.writerto, config)
 	} else {
 		c.writertoCompress = c.writerto
 	}
@@ -350,8 +518,20 @@ func (ch *conn) hello() error {
 	ch.writer.Uvarint(dbmsVersionMinor)
 	ch.writer.Uvarint(dbmsVersionRevision)
 	ch.writer.String(ch.config.Database)
-	ch.writer.String(ch.config.User)
-	ch.writer.String(ch.config.Password)
+	if ch.config.ClusterSecret != "" {
+		var err error
+		if ch.interserverSalt, err = newInterserverSalt(); err != nil {
+			return fmt.Errorf("generate interserver salt: %w", err)
+		}
+		ch.writer.String(interserverUserMarker)
+		// password
+		ch.writer.String("")
+		ch.writer.String(ch.config.Cluster)
+		ch.writer.String(ch.interserverSalt)
+	} else {
+		ch.writer.String(ch.config.User)
+		ch.writer.String(ch.config.Password)
+	}
 
 	if _, err := ch.writer.WriteTo(ch.writerto); err != nil {
 		return fmt.Errorf("write hello: %w", err)
@@ -364,6 +544,15 @@ func (ch *conn) hello() error {
 	if ch.serverInfo.Revision == 0 {
 		return &unexpectedPacket{expected: "serverHello", actual: res}
 	}
+	if ch.config.ClusterSecret != "" && ch.serverInfo.Revision < dbmsMinRevisionWithInterserverSecret {
+		return ErrInterserverSecretNotSupported
+	}
+	if ch.serverInfo.Revision >= dbmsMinProtocolVersionWithAddendum {
+		ch.writer.String(ch.config.QuotaKey)
+		if _, err := ch.writer.WriteTo(ch.writerto); err != nil {
+			return fmt.Errorf("write hello addendum: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -402,13 +591,27 @@ func (ch *conn) unlock() {
 }
 
 func (ch *conn) sendQueryWithOption(
-	ctx context.Context, //nolint:unparam
-	query,
-	queryID string,
-	settings *setting.Settings,
+	ctx context.Context,
+	query string,
+	queryOptions *QueryOptions,
 ) error {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	if ch.canceled {
+		return ctx.Err()
+	}
+	ch.inFlight = true
+	ch.onLog = queryOptions.OnLog
+	ch.onProfileEvents = queryOptions.OnProfileEvents
+	ch.tableColumns = nil
+
+	initialUser := queryOptions.InitialUser
+	if initialUser == "" && ch.config.ClusterSecret != "" {
+		initialUser = ch.config.User
+	}
+
 	ch.writer.Uvarint(clientQuery)
-	ch.writer.String(queryID)
+	ch.writer.String(queryOptions.QueryID)
 	if ch.serverInfo.Revision >= dbmsMinRevisionWithClientInfo {
 		if ch.clientInfo == nil {
 			ch.clientInfo = &ClientInfo{}
@@ -416,24 +619,49 @@ func (ch *conn) sendQueryWithOption(
 
 		ch.clientInfo.fillOSUserHostNameAndVersionInfo()
 		ch.clientInfo.ClientName = ch.config.Database + " " + ch.config.ClientName
+		ch.clientInfo.QuotaKey = ch.config.QuotaKey
+		if queryOptions.QuotaKey != "" {
+			ch.clientInfo.QuotaKey = queryOptions.QuotaKey
+		}
+		if queryOptions.ClientName != "" {
+			ch.clientInfo.ClientName = queryOptions.ClientName
+		}
+		if queryOptions.OSUser != "" {
+			ch.clientInfo.OSUser = queryOptions.OSUser
+		}
+		if queryOptions.ClientHostname != "" {
+			ch.clientInfo.ClientHostname = queryOptions.ClientHostname
+		}
+		ch.clientInfo.TraceContext = queryOptions.TraceContext
+		ch.clientInfo.InitialUser = initialUser
+		ch.clientInfo.InitialQueryID = queryOptions.InitialQueryID
+		ch.clientInfo.secondaryQuery = ch.config.ClusterSecret != ""
 
 		ch.clientInfo.write(ch)
 	}
 
 	// setting
-	if settings != nil {
+	// the settings of the compression are sent before the settings of the query, so the query can override them.
+	// the settings of the deadline are sent after them, so a larger max_execution_time of the query is overridden
+	settingsAsString := ch.serverInfo.Revision >= dbmsMinRevisionWithSettingsSerializedAsStrings
+	if ch.compress {
+		writeCompressSettings(ch.writer, ch.config, settingsAsString)
+	}
+	if queryOptions.Settings != nil {
 		//nolint:errcheck // no need for bytes.Buffer
-		settings.WriteTo(ch.writer.Output(),
-			ch.serverInfo.Revision >= dbmsMinRevisionWithSettingsSerializedAsStrings)
+		queryOptions.Settings.WriteTo(ch.writer.Output(), settingsAsString)
+	}
+	if ch.config.DeadlineExecutionTime {
+		writeDeadlineSettings(ctx, ch.writer, queryOptions.Settings, settingsAsString)
 	}
 
 	ch.writer.String("")
 
 	if ch.serverInfo.Revision >= dbmsMinRevisionWithInterserverSecret {
-		ch.writer.String("")
+		ch.writeInterserverHash(query, queryOptions.QueryID, initialUser)
 	}
 
-	ch.writer.Uvarint(uint64(queryProcessingStageComplete))
+	ch.writer.Uvarint(queryOptions.Stage.protocolValue())
 
 	// compression
 	if ch.compress {
@@ -444,13 +672,39 @@ func (ch *conn) sendQueryWithOption(
 
 	ch.writer.String(query)
 
-	return ch.sendData(newBlock(), 0)
+	if ch.serverInfo.Revision >= dbmsMinProtocolVersionWithParameters {
+		writeParameters(ch.writer, queryOptions.Parameters)
+	}
+
+	for _, table := range queryOptions.ExternalTables {
+		if err := table.write(ch); err != nil {
+			return err
+		}
+	}
+
+	return ch.sendData(newBlock(), "", 0)
 }
 
-func (ch *conn) sendData(block *Block, numRows int) error {
+// checkQueryOptions checks the query options before anything is sent to the server.
+func (ch *conn) checkQueryOptions(queryOptions *QueryOptions) error {
+	if len(queryOptions.Parameters) > 0 && ch.serverInfo.Revision < dbmsMinProtocolVersionWithParameters {
+		return ErrParametersNotSupported
+	}
+	if queryOptions.Settings != nil && queryOptions.Settings.HasCustom() &&
+		ch.serverInfo.Revision < dbmsMinRevisionWithSettingsSerializedAsStrings {
+		return ErrCustomSettingsNotSupported
+	}
+	for _, table := range queryOptions.ExternalTables {
+		if len(table.columns) == 0 {
+			return ErrInsertMinColumn
+		}
+	}
+	return nil
+}
+
+func (ch *conn) sendData(block *Block, name string, numRows int) error {
 	ch.writer.Uvarint(clientData)
-	// name
-	ch.writer.String("")
+	ch.writer.String(name)
 
 	// if compress enable we must send to this part with uncompress data
 	if ch.compress {
@@ -462,6 +716,140 @@ func (ch *conn) sendData(block *Block, numRows int) error {
 	return block.writeHeader(ch, numRows)
 }
 
+// watch watches ctx for the next request to the server. If ctx is canceled the running query is canceled on the server.
+func (ch *conn) watch(ctx context.Context) {
+	ch.writeMu.Lock()
+	// a context that is already done must not send anything
+	ch.canceled = ctx.Err() != nil
+	ch.writeMu.Unlock()
+	ch.contextWatcher.Watch(ctx)
+}
+
+// cancel is called by the context watcher when the context of the query is canceled. It sends the cancel packet if a
+// query is running on the server and gives the server CancelTimeout to end it before the connection is interrupted.
+// Otherwise the connection is interrupted immediately.
+func (ch *conn) cancel() {
+	if ch.config.CancelTimeout == 0 {
+		ch.setFixedDeadline(time.Date(1, 1, 1, 1, 1, 1, 1, time.UTC))
+	} else {
+		// a write that holds writeMu can't block the cancellation longer than CancelTimeout
+		ch.setFixedDeadline(time.Now().Add(ch.config.CancelTimeout))
+	}
+
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	ch.canceled = true
+	if ch.config.CancelTimeout == 0 {
+		return
+	}
+	if !ch.inFlight {
+		// there is no query to cancel (e.g. ping)
+		ch.setFixedDeadline(time.Date(1, 1, 1, 1, 1, 1, 1, time.UTC))
+		return
+	}
+	if _, err := ch.writerto.Write([]byte{clientCancel}); err != nil {
+		ch.setFixedDeadline(time.Date(1, 1, 1, 1, 1, 1, 1, time.UTC))
+	}
+}
+
+func (ch *conn) isCanceled() bool {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	return ch.canceled
+}
+
+func (ch *conn) isInFlight() bool {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	return ch.inFlight
+}
+
+// endQuery is called when the server ends the query with the end of stream or an exception.
+func (ch *conn) endQuery() {
+	ch.writeMu.Lock()
+	ch.inFlight = false
+	ch.writeMu.Unlock()
+}
+
+// finishCanceled is called instead of the normal error handling when the query was canceled. res and err are the
+// result of the last operation. It reads the rest of the response to keep the connection usable, closes the connection
+// if that is not possible and returns the context error. The context error is wrapped with the error of the
+// interrupted connection if it is closed.
+func (ch *conn) finishCanceled(ctx context.Context, res interface{}, err error) error {
+	if _, ok := err.(*ChError); ok {
+		// the query was ended by the server
+		return ctx.Err()
+	}
+	// ctx.Err() means nothing was written after the cancellation
+	if err == nil || err == ctx.Err() {
+		err = nil
+		if block, ok := res.(*Block); ok {
+			err = block.skipColumns(ch)
+		}
+		if err == nil {
+			err = ch.drain(0)
+		}
+	}
+	if err != nil {
+		ch.Close(context.Background())
+		return &canceledError{ctxErr: ctx.Err(), err: err}
+	}
+	return ctx.Err()
+}
+
+// drain reads and discards the packets of the current query until the server ends it. If maxBytes is not zero, it
+// returns ErrDrainLimit when more than maxBytes are read from the connection.
+func (ch *conn) drain(maxBytes int64) error {
+	start := ch.readCounter.n
+	for ch.isInFlight() {
+		if maxBytes > 0 && ch.readCounter.n-start > maxBytes {
+			return ErrDrainLimit
+		}
+		res, err := ch.reciveAndProccessData(nil)
+		if err != nil {
+			if _, ok := err.(*ChError); ok {
+				return nil
+			}
+			return err
+		}
+		if block, ok := res.(*Block); ok {
+			if err := block.skipColumns(ch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// discard cancels the current query and discards the rest of its result in the limits of CloseDrainTimeout and
+// CloseDrainMaxBytes. The connection must be closed if it returns an error.
+func (ch *conn) discard() error {
+	if ch.config.CloseDrainTimeout == 0 {
+		return ErrDrainLimit
+	}
+	ch.setFixedDeadline(time.Now().Add(ch.config.CloseDrainTimeout))
+	defer ch.setFixedDeadline(time.Time{})
+	ch.writeMu.Lock()
+	_, err := ch.writerto.Write([]byte{clientCancel})
+	ch.writeMu.Unlock()
+	if err != nil {
+		return &writeError{"cancel: write packet", err}
+	}
+	return ch.drain(ch.config.CloseDrainMaxBytes)
+}
+
+// readCounter counts the bytes that are read from the connection.
+type readCounter struct {
+	r io.Reader
+	n int64
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 func (ch *conn) Close(ctx context.Context) error {
 	if ch.status == connStatusClosed {
 		return nil
@@ -475,10 +863,35 @@ func (ch *conn) Close(ctx context.Context) error {
 	return ch.conn.Close()
 }
 
-func (ch *conn) readTableColumn() {
-	ch.reader.String() //nolint:errcheck //no needed
-	ch.reader.String() //nolint:errcheck //no needed
+// readPartUUIDs reads the ids of the parts that the server has read. they are only used by the distributed queries
+func (ch *conn) readPartUUIDs() error {
+	n, err := ch.reader.Uvarint()
+	if err != nil {
+		return &readError{"part uuids: read count", err}
+	}
+	var uuid [16]byte
+	for i := uint64(0); i < n; i++ {
+		if _, err := io.ReadFull(ch.reader, uuid[:]); err != nil {
+			return &readError{"part uuids: read uuid", err}
+		}
+	}
+	return nil
 }
+
+// sendReadTaskResponse replies to a ReadTaskRequest. chconn is not a cluster coordinator and has no task to give, so
+// it always sends an empty response that means there is no more task.
+func (ch *conn) sendReadTaskResponse() error {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	ch.writer.Uvarint(clientReadTaskResponse)
+	ch.writer.Uvarint(dbmsClusterProcessingProtocolVersion)
+	ch.writer.String("")
+	if _, err := ch.writer.WriteTo(ch.writerto); err != nil {
+		return &writeError{"read task response: write packet", err}
+	}
+	return nil
+}
+
 func (ch *conn) reciveAndProccessData(onProgress func(*Progress)) (interface{}, error) {
 	packet, err := ch.reader.Uvarint()
 	if err != nil {
@@ -487,6 +900,12 @@ func (ch *conn) reciveAndProccessData(onProgress func(*Progress)) (interface{},
 	switch packet {
 	case serverData, serverTotals, serverExtremes:
 		block := newBlock()
+		switch packet {
+		case serverTotals:
+			block.Kind = BlockTotals
+		case serverExtremes:
+			block.Kind = BlockExtremes
+		}
 		err = block.read(ch)
 		return block, err
 	case serverProfileInfo:
@@ -507,19 +926,59 @@ func (ch *conn) reciveAndProccessData(onProgress func(*Progress)) (interface{},
 		return nil, err
 	case serverPong:
 		return &pong{}, err
+	case serverTablesStatusResponse:
+		status := make(tablesStatus)
+		err = status.read(ch)
+		return status, err
 	case serverException:
+		// the server ends the query with the exception and the connection is still usable
 		err := &ChError{}
-		defer ch.Close(context.Background())
+		ch.endQuery()
 		if errRead := err.read(ch.reader); errRead != nil {
 			return nil, errRead
 		}
 		return nil, err
 	case serverEndOfStream:
+		ch.endQuery()
 		return nil, nil
 
 	case serverTableColumns:
-		ch.readTableColumn()
+		if err := ch.readTableColumns(); err != nil {
+			return nil, err
+		}
 
+		return ch.reciveAndProccessData(onProgress)
+	case serverLog:
+		if err := ch.readServerLog(); err != nil {
+			return nil, err
+		}
+		return ch.reciveAndProccessData(onProgress)
+	case serverProfileEvents:
+		if err := ch.readProfileEvents(); err != nil {
+			return nil, err
+		}
+		return ch.reciveAndProccessData(onProgress)
+	case serverPartUUIDs:
+		if err := ch.readPartUUIDs(); err != nil {
+			return nil, err
+		}
+		return ch.reciveAndProccessData(onProgress)
+	case serverReadTaskRequest:
+		if err := ch.sendReadTaskResponse(); err != nil {
+			return nil, err
+		}
+		return ch.reciveAndProccessData(onProgress)
+	case serverTimezoneUpdate:
+		timezone, err := ch.reader.String()
+		if err != nil {
+			return nil, &readError{"timezone update: read timezone", err}
+		}
+		ch.serverInfo.SessionTimezone = nil
+		if timezone != "" {
+			if ch.serverInfo.SessionTimezone, err = time.LoadLocation(timezone); err != nil {
+				return nil, &readError{"timezone update: could not load time location", err}
+			}
+		}
 		return ch.reciveAndProccessData(onProgress)
 	}
 	return nil, &notImplementedPacket{packet: packet}
@@ -529,12 +988,14 @@ var emptyOnProgress = func(*Progress) {
 
 }
 
-func (ch *conn) Exec(ctx context.Context, query string) (interface{}, error) {
-	return ch.ExecCallback(ctx, query, nil, "", nil)
+func (ch *conn) Exec(ctx context.Context, query string) (*ExecResult, error) {
+	return ch.ExecWithOption(ctx, query, &QueryOptions{})
 }
 
-func (ch *conn) ExecWithSetting(ctx context.Context, query string, settings *setting.Settings) (interface{}, error) {
-	return ch.ExecCallback(ctx, query, settings, "", nil)
+func (ch *conn) ExecWithSetting(ctx context.Context, query string, settings *setting.Settings) (*ExecResult, error) {
+	return ch.ExecWithOption(ctx, query, &QueryOptions{
+		Settings: settings,
+	})
 }
 
 func (ch *conn) ExecCallback(
@@ -543,14 +1004,34 @@ func (ch *conn) ExecCallback(
 	settings *setting.Settings,
 	queryID string,
 	onProgress func(*Progress),
-) (interface{}, error) {
-	err := ch.lock()
+) (*ExecResult, error) {
+	return ch.ExecWithOption(ctx, query, &QueryOptions{
+		QueryID:    queryID,
+		Settings:   settings,
+		OnProgress: onProgress,
+	})
+}
+
+// ExecWithOption executes a query without returning any rows with the query options.
+func (ch *conn) ExecWithOption(
+	ctx context.Context,
+	query string,
+	queryOptions *QueryOptions,
+) (*ExecResult, error) {
+	if queryOptions == nil {
+		queryOptions = &QueryOptions{}
+	}
+	err := ch.checkQueryOptions(queryOptions)
+	if err != nil {
+		return nil, err
+	}
+	err = ch.lock()
 	if err != nil {
 		return nil, err
 	}
 	defer ch.unlock()
 
-	ch.contextWatcher.Watch(ctx)
+	ch.watch(ctx)
 	defer ch.contextWatcher.Unwatch()
 	var hasError bool
 	defer func() {
@@ -559,24 +1040,58 @@ func (ch *conn) ExecCallback(
 		}
 	}()
 
-	err = ch.sendQueryWithOption(ctx, query, queryID, settings)
-	if err != nil {
-		hasError = true
-		return nil, err
+	// the query id is generated, so the result can be found in system.query_log
+	if queryOptions.QueryID == "" {
+		options := *queryOptions
+		options.QueryID = uuid.New().String()
+		queryOptions = &options
 	}
-	if onProgress == nil {
-		onProgress = emptyOnProgress
+	result := &ExecResult{
+		QueryID: queryOptions.QueryID,
 	}
-	if err != nil {
-		hasError = true
-		return nil, err
+	start := time.Now()
+	err = ch.sendQueryWithOption(ctx, query, queryOptions)
+	if ch.isCanceled() {
+		return nil, ch.finishCanceled(ctx, nil, err)
 	}
-	res, err := ch.reciveAndProccessData(onProgress)
 	if err != nil {
 		hasError = true
 		return nil, err
 	}
-	return res, nil
+	// the response is read until the end of stream, so nothing is left unread for the next query
+	for {
+		res, err := ch.reciveAndProccessData(nil)
+		if ch.isCanceled() {
+			return nil, ch.finishCanceled(ctx, res, err)
+		}
+		if err != nil {
+			hasError = needsClose(err)
+			return nil, err
+		}
+		switch res := res.(type) {
+		case nil:
+			result.Elapsed = time.Since(start)
+			return result, nil
+		case *Progress:
+			result.addProgress(res)
+			if queryOptions.OnProgress != nil {
+				queryOptions.OnProgress(res)
+			}
+		case *Profile:
+			result.Profile = res
+			if queryOptions.OnProfile != nil {
+				queryOptions.OnProfile(res)
+			}
+		case *Block:
+			if err := res.skipColumns(ch); err != nil {
+				hasError = true
+				return nil, err
+			}
+		default:
+			hasError = true
+			return nil, &unexpectedPacket{expected: "serverEndOfStream", actual: res}
+		}
+	}
 }
 
 // Insert send query for insert and prepare insert stmt
@@ -586,11 +1101,26 @@ func (ch *conn) Insert(ctx context.Context, query string) (InsertStmt, error) {
 
 // Insert send query for insert and prepare insert stmt with setting option
 func (ch *conn) InsertWithSetting(ctx context.Context, query string, settings *setting.Settings, queryID string) (InsertStmt, error) {
-	err := ch.lock()
+	return ch.InsertWithOption(ctx, query, &QueryOptions{
+		QueryID:  queryID,
+		Settings: settings,
+	})
+}
+
+// InsertWithOption send query for insert and prepare insert stmt with the query options
+func (ch *conn) InsertWithOption(ctx context.Context, query string, queryOptions *QueryOptions) (InsertStmt, error) {
+	if queryOptions == nil {
+		queryOptions = &QueryOptions{}
+	}
+	err := ch.checkQueryOptions(queryOptions)
 	if err != nil {
 		return nil, err
 	}
-	ch.contextWatcher.Watch(ctx)
+	err = ch.lock()
+	if err != nil {
+		return nil, err
+	}
+	ch.watch(ctx)
 	defer ch.contextWatcher.Unwatch()
 	var hasError bool
 	defer func() {
@@ -598,18 +1128,35 @@ func (ch *conn) InsertWithSetting(ctx context.Context, query string, settings *s
 			ch.Close(context.Background())
 		}
 	}()
-	err = ch.sendQueryWithOption(ctx, query, queryID, settings)
+	err = ch.sendQueryWithOption(ctx, query, queryOptions)
+	if ch.isCanceled() {
+		err = ch.finishCanceled(ctx, nil, err)
+		ch.unlock()
+		return nil, err
+	}
 	if err != nil {
 		hasError = true
 		return nil, err
 	}
 
+	onProgress := queryOptions.OnProgress
+	if onProgress == nil {
+		onProgress = emptyOnProgress
+	}
 	var blockData *Block
 	for {
 		var res interface{}
-		res, err = ch.reciveAndProccessData(emptyOnProgress)
+		res, err = ch.reciveAndProccessData(onProgress)
+		if ch.isCanceled() {
+			err = ch.finishCanceled(ctx, res, err)
+			ch.unlock()
+			return nil, err
+		}
 		if err != nil {
-			hasError = true
+			hasError = needsClose(err)
+			if !hasError {
+				ch.unlock()
+			}
 			return nil, err
 		}
 		if b, ok := res.(*Block); ok {
@@ -617,10 +1164,10 @@ func (ch *conn) InsertWithSetting(ctx context.Context, query string, settings *s
 			break
 		}
 
-		if _, ok := res.(*Profile); ok {
-			continue
-		}
-		if _, ok := res.(*Progress); ok {
+		if profile, ok := res.(*Profile); ok {
+			if queryOptions.OnProfile != nil {
+				queryOptions.OnProfile(profile)
+			}
 			continue
 		}
 		hasError = true
@@ -633,24 +1180,75 @@ func (ch *conn) InsertWithSetting(ctx context.Context, query string, settings *s
 		return nil, err
 	}
 	return &insertStmt{
-		block:      blockData,
-		conn:       ch,
-		query:      query,
-		queryID:    "",
-		stage:      queryProcessingStageComplete,
-		settings:   settings,
-		clientInfo: nil,
+		block:        blockData,
+		conn:         ch,
+		query:        query,
+		queryID:      queryOptions.QueryID,
+		stage:        QueryProcessingStageComplete,
+		settings:     queryOptions.Settings,
+		clientInfo:   nil,
+		onProgress:   onProgress,
+		onProfile:    queryOptions.OnProfile,
+		tableColumns: ch.tableColumns,
 	}, nil
 }
 
+// InsertOmitDefaults send query for insert into the table without the columns that have a default value and prepare
+// insert stmt. columns are the columns with a default value that are inserted too.
+func (ch *conn) InsertOmitDefaults(
+	ctx context.Context,
+	table TableName,
+	columns []string,
+	queryOptions *QueryOptions,
+) (InsertStmt, error) {
+	stmt, err := ch.InsertWithOption(ctx, "INSERT INTO "+table.quote()+" VALUES", queryOptions)
+	if err != nil {
+		return nil, err
+	}
+	s := stmt.(*insertStmt)
+	names := insertColumnNames(s.block.Columns, s.tableColumns, columns)
+	if len(names) == len(s.block.Columns) {
+		return s, nil
+	}
+
+	// the server only fills the columns that are not in the column list of the query
+	if err := s.Close(ctx); err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = backQuote(name)
+	}
+	return ch.InsertWithOption(ctx, "INSERT INTO "+table.quote()+" ("+strings.Join(names, ", ")+") VALUES", queryOptions)
+}
+
+// insertColumnNames returns the columns of the insert block that have no default value or are in columns.
+func insertColumnNames(blockColumns []*Column, tableColumns []TableColumn, columns []string) []string {
+	hasDefault := make(map[string]bool, len(tableColumns))
+	for i := range tableColumns {
+		hasDefault[tableColumns[i].Name] = tableColumns[i].HasDefault()
+	}
+	for _, name := range columns {
+		hasDefault[name] = false
+	}
+	names := make([]string, 0, len(blockColumns))
+	for _, col := range blockColumns {
+		if !hasDefault[col.Name] {
+			names = append(names, col.Name)
+		}
+	}
+	return names
+}
+
 // Select send query for select and prepare SelectStmt
 func (ch *conn) Select(ctx context.Context, query string) (SelectStmt, error) {
-	return ch.SelectCallback(ctx, query, nil, "", nil, nil)
+	return ch.SelectWithOption(ctx, query, &QueryOptions{})
 }
 
 // Select send query for select and prepare SelectStmt with settion option
 func (ch *conn) SelectWithSetting(ctx context.Context, query string, settings *setting.Settings) (SelectStmt, error) {
-	return ch.SelectCallback(ctx, query, settings, "", nil, nil)
+	return ch.SelectWithOption(ctx, query, &QueryOptions{
+		Settings: settings,
+	})
 }
 
 // Select send query for select and prepare SelectStmt on progress and on profile callback
@@ -662,30 +1260,58 @@ func (ch *conn) SelectCallback(
 	onProgress func(*Progress),
 	onProfile func(*Profile),
 ) (SelectStmt, error) {
-	err := ch.lock()
+	return ch.SelectWithOption(ctx, query, &QueryOptions{
+		QueryID:    queryID,
+		Settings:   settings,
+		OnProgress: onProgress,
+		OnProfile:  onProfile,
+	})
+}
+
+// SelectWithOption send query for select and prepare SelectStmt with the query options
+func (ch *conn) SelectWithOption(
+	ctx context.Context,
+	query string,
+	queryOptions *QueryOptions,
+) (SelectStmt, error) {
+	if queryOptions == nil {
+		queryOptions = &QueryOptions{}
+	}
+	err := ch.checkQueryOptions(queryOptions)
+	if err != nil {
+		return nil, err
+	}
+	err = ch.lock()
 	if err != nil {
 		return nil, err
 	}
 
-	ch.contextWatcher.Watch(ctx)
-	defer ch.contextWatcher.Unwatch()
-	var hasError bool
-	defer func() {
-		if hasError {
-			ch.Close(context.Background())
-		}
-	}()
-	err = ch.sendQueryWithOption(ctx, query, queryID, settings)
+	// the context is watched until the stmt is closed, so the cancellation and the deadline also apply to reading
+	// the blocks
+	ch.watch(ctx)
+	err = ch.sendQueryWithOption(ctx, query, queryOptions)
+	if ch.isCanceled() {
+		err = ch.finishCanceled(ctx, nil, err)
+		ch.contextWatcher.Unwatch()
+		ch.unlock()
+		return nil, err
+	}
 	if err != nil {
-		hasError = true
+		ch.contextWatcher.Unwatch()
+		ch.Close(context.Background())
 		return nil, err
 	}
-	return &selectStmt{
-		conn:       ch,
-		query:      query,
-		onProgress: onProgress,
-		onProfile:  onProfile,
-		queryID:    queryID,
-		clientInfo: nil,
-	}, nil
+	stmt := &selectStmt{
+		ctx:             ctx,
+		conn:            ch,
+		query:           query,
+		onProgress:      queryOptions.OnProgress,
+		onProfile:       queryOptions.OnProfile,
+		onProfileEvents: queryOptions.OnProfileEvents,
+		profileEvents:   ProfileEvents{},
+		queryID:         queryOptions.QueryID,
+		clientInfo:      nil,
+	}
+	ch.onProfileEvents = stmt.addProfileEvents
+	return stmt, nil
 }
@@ -8,9 +8,11 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
 )
 
 func TestConnect(t *testing.T) {
@@ -132,7 +134,7 @@ func TestEndOfStream(t *testing.T) {
 			) engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 }
 
 func TestException(t *testing.T) {
@@ -151,6 +153,28 @@ func TestException(t *testing.T) {
 	require.True(t, errors.As(err, &chError))
 	require.Equal(t, chError.Code, int32(62))
 	require.Equal(t, chError.Name, "DB::Exception")
+
+	// the connection is still usable after the exception
+	require.False(t, conn.IsClosed())
+	require.NoError(t, conn.Ping(context.Background()))
+
+	_, err = conn.Insert(context.Background(), `INSERT INTO chconn_test_unknown_table (id) VALUES`)
+	require.True(t, errors.As(err, &chError))
+	require.False(t, conn.IsClosed())
+
+	stmt, err := conn.Select(context.Background(), `SELECT throwIf(number = 1) FROM system.numbers LIMIT 10`)
+	require.NoError(t, err)
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(column.NewUint8(false)))
+	}
+	require.True(t, errors.As(stmt.Err(), &chError))
+	stmt.Close()
+	require.False(t, conn.IsClosed())
+
+	res, err = conn.Exec(context.Background(), `SELECT 1`)
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	conn.Close(context.Background())
 }
 
 func TestTlsPreferConnect(t *testing.T) {
@@ -289,3 +313,53 @@ func TestRecivePackError(t *testing.T) {
 	require.EqualError(t, err, "packet: read packet type (timeout)")
 	require.Nil(t, res)
 }
+
+func TestCancelQuery(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	res, err := conn.Exec(ctx, `SELECT count() FROM system.numbers`)
+	require.Nil(t, res)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// the connection must be usable after cancel
+	require.False(t, conn.IsClosed())
+	require.NoError(t, conn.Ping(context.Background()))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	res, err = conn.Exec(ctx, `SELECT 1`)
+	require.Nil(t, res)
+	require.True(t, errors.Is(err, context.Canceled))
+	require.False(t, conn.IsClosed())
+
+	stmt, err := conn.Select(context.Background(), `SELECT number FROM system.numbers LIMIT 5`)
+	require.NoError(t, err)
+	var n int
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(column.NewUint64(false)))
+		n += stmt.RowsInBlock()
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	require.Equal(t, 5, n)
+
+	// the context of the select is watched while the blocks are read
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	stmt, err = conn.Select(ctx, `SELECT number FROM system.numbers`)
+	require.NoError(t, err)
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(column.NewUint64(false)))
+	}
+	require.True(t, errors.Is(stmt.Err(), context.DeadlineExceeded))
+	stmt.Close()
+	require.False(t, conn.IsClosed())
+	require.NoError(t, conn.Ping(context.Background()))
+}
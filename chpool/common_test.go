@@ -19,13 +19,13 @@ func waitForReleaseToComplete() {
 }
 
 type execer interface {
-	Exec(ctx context.Context, sql string) (interface{}, error)
+	Exec(ctx context.Context, sql string) (*chconn.ExecResult, error)
 }
 
 func testExec(t *testing.T, db execer) {
 	results, err := db.Exec(context.Background(), "SET enable_http_compression=1")
 	require.NoError(t, err)
-	assert.EqualValues(t, nil, results)
+	assert.NotNil(t, results)
 }
 
 type selecter interface {
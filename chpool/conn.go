@@ -19,7 +19,10 @@ type Conn interface {
 		query string,
 		settings *setting.Settings,
 		queryID string,
-		onProgress func(*chconn.Progress)) (interface{}, error)
+		onProgress func(*chconn.Progress)) (*chconn.ExecResult, error)
+	// ExecWithOption executes a query without returning any rows with the query options.
+	// NOTE: don't use it for insert and select query
+	ExecWithOption(ctx context.Context, query string, queryOptions *chconn.QueryOptions) (*chconn.ExecResult, error)
 	// Select executes a query with the setting option, on progress callback, on profile callback and return select stmt.
 	// NOTE: only use for select query
 	SelectCallback(
@@ -30,11 +33,26 @@ type Conn interface {
 		onProgress func(*chconn.Progress),
 		onProfile func(*chconn.Profile),
 	) (chconn.SelectStmt, error)
+	// SelectWithOption executes a query with the query options and return select stmt.
+	// NOTE: only use for select query
+	SelectWithOption(ctx context.Context, query string, queryOptions *chconn.QueryOptions) (chconn.SelectStmt, error)
 	// InsertWithSetting executes a query with the setting option and return insert stmt.
 	// NOTE: only use for insert query
 	InsertWithSetting(ctx context.Context, query string, settings *setting.Settings, queryID string) (chconn.InsertStmt, error)
+	// InsertWithOption executes a query with the query options and return insert stmt.
+	// NOTE: only use for insert query
+	InsertWithOption(ctx context.Context, query string, queryOptions *chconn.QueryOptions) (chconn.InsertStmt, error)
+	// InsertOmitDefaults prepares an insert into the table without the columns that have a default value and return
+	// insert stmt. columns are the columns with a default value that are inserted too.
+	InsertOmitDefaults(
+		ctx context.Context,
+		table chconn.TableName,
+		columns []string,
+		queryOptions *chconn.QueryOptions,
+	) (chconn.InsertStmt, error)
 	Conn() chconn.Conn
 	Ping(ctx context.Context) error
+	TablesStatus(ctx context.Context, tables []chconn.TableName) (map[chconn.TableName]chconn.TableStatus, error)
 }
 type conn struct {
 	res *puddle.Resource
@@ -77,14 +95,29 @@ func (c *conn) ExecCallback(
 	query string,
 	settings *setting.Settings,
 	queryID string,
-	onProgress func(*chconn.Progress)) (interface{}, error) {
+	onProgress func(*chconn.Progress)) (*chconn.ExecResult, error) {
 	return c.Conn().ExecCallback(ctx, query, settings, queryID, onProgress)
 }
 
+func (c *conn) ExecWithOption(
+	ctx context.Context,
+	query string,
+	queryOptions *chconn.QueryOptions,
+) (*chconn.ExecResult, error) {
+	return c.Conn().ExecWithOption(ctx, query, queryOptions)
+}
+
 func (c *conn) Ping(ctx context.Context) error {
 	return c.Conn().Ping(ctx)
 }
 
+func (c *conn) TablesStatus(
+	ctx context.Context,
+	tables []chconn.TableName,
+) (map[chconn.TableName]chconn.TableStatus, error) {
+	return c.Conn().TablesStatus(ctx, tables)
+}
+
 func (c *conn) SelectCallback(
 	ctx context.Context,
 	query string,
@@ -103,6 +136,21 @@ func (c *conn) SelectCallback(
 	}, nil
 }
 
+func (c *conn) SelectWithOption(
+	ctx context.Context,
+	query string,
+	queryOptions *chconn.QueryOptions,
+) (chconn.SelectStmt, error) {
+	s, err := c.Conn().SelectWithOption(ctx, query, queryOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &selectStmt{
+		SelectStmt: s,
+		conn:       c,
+	}, nil
+}
+
 func (c *conn) InsertWithSetting(ctx context.Context, query string, settings *setting.Settings, queryID string) (chconn.InsertStmt, error) {
 	s, err := c.Conn().InsertWithSetting(ctx, query, settings, queryID)
 	if err != nil {
@@ -114,6 +162,33 @@ func (c *conn) InsertWithSetting(ctx context.Context, query string, settings *se
 	}, nil
 }
 
+func (c *conn) InsertWithOption(ctx context.Context, query string, queryOptions *chconn.QueryOptions) (chconn.InsertStmt, error) {
+	s, err := c.Conn().InsertWithOption(ctx, query, queryOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &insertStmt{
+		InsertStmt: s,
+		conn:       c,
+	}, nil
+}
+
+func (c *conn) InsertOmitDefaults(
+	ctx context.Context,
+	table chconn.TableName,
+	columns []string,
+	queryOptions *chconn.QueryOptions,
+) (chconn.InsertStmt, error) {
+	s, err := c.Conn().InsertOmitDefaults(ctx, table, columns, queryOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &insertStmt{
+		InsertStmt: s,
+		conn:       c,
+	}, nil
+}
+
 func (c *conn) Conn() chconn.Conn {
 	return c.connResource().conn
 }
@@ -16,3 +16,8 @@ func (s *insertStmt) Commit(ctx context.Context, columns ...column.Column) error
 	defer s.conn.Release()
 	return s.InsertStmt.Commit(ctx, columns...)
 }
+
+func (s *insertStmt) Close(ctx context.Context) error {
+	defer s.conn.Release()
+	return s.InsertStmt.Close(ctx)
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"runtime"
 	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/puddle"
 	"github.com/vahid-sohrabloo/chconn"
+	"github.com/vahid-sohrabloo/chconn/column"
 	"github.com/vahid-sohrabloo/chconn/setting"
 )
 
@@ -20,6 +24,8 @@ var defaultMinConns = int32(0)
 var defaultMaxConnLifetime = time.Hour
 var defaultMaxConnIdleTime = time.Minute * 30
 var defaultHealthCheckPeriod = time.Minute
+var defaultInsertMaxRetries = 3
+var defaultInsertRetryDelay = time.Second
 
 type connResource struct {
 	conn  chconn.Conn
@@ -54,10 +60,10 @@ type Pool interface {
 	AcquireAllIdle(ctx context.Context) []Conn
 	// Exec executes a query without returning any rows.
 	// NOTE: don't use it for insert and select query
-	Exec(ctx context.Context, sql string) (interface{}, error)
+	Exec(ctx context.Context, sql string) (*chconn.ExecResult, error)
 	// ExecWithSetting executes a query without returning any rows with the setting option.
 	// NOTE: don't use it for insert and select query
-	ExecWithSetting(ctx context.Context, query string, settings *setting.Settings) (interface{}, error)
+	ExecWithSetting(ctx context.Context, query string, settings *setting.Settings) (*chconn.ExecResult, error)
 	// ExecCallback executes a query without returning any rows with the setting option and on progress callback.
 	// NOTE: don't use it for insert and select query
 	ExecCallback(
@@ -66,7 +72,10 @@ type Pool interface {
 		settings *setting.Settings,
 		queryID string,
 		onProgress func(*chconn.Progress),
-	) (interface{}, error)
+	) (*chconn.ExecResult, error)
+	// ExecWithOption executes a query without returning any rows with the query options.
+	// NOTE: don't use it for insert and select query
+	ExecWithOption(ctx context.Context, query string, queryOptions *chconn.QueryOptions) (*chconn.ExecResult, error)
 	// Select executes a query and return select stmt.
 	// NOTE: only use for select query
 	Select(ctx context.Context, query string) (chconn.SelectStmt, error)
@@ -83,14 +92,38 @@ type Pool interface {
 		onProgress func(*chconn.Progress),
 		onProfile func(*chconn.Profile),
 	) (chconn.SelectStmt, error)
+	// SelectWithOption executes a query with the query options and return select stmt.
+	// NOTE: only use for select query
+	SelectWithOption(ctx context.Context, query string, queryOptions *chconn.QueryOptions) (chconn.SelectStmt, error)
 	// Insert executes a query and return insert stmt.
 	// NOTE: only use for insert query
 	Insert(ctx context.Context, query string) (chconn.InsertStmt, error)
 	// InsertWithSetting executes a query with the setting option and return insert stmt.
 	// NOTE: only use for insert query
 	InsertWithSetting(ctx context.Context, query string, settings *setting.Settings, queryID string) (chconn.InsertStmt, error)
+	// InsertWithOption executes a query with the query options and return insert stmt.
+	// NOTE: only use for insert query
+	InsertWithOption(ctx context.Context, query string, queryOptions *chconn.QueryOptions) (chconn.InsertStmt, error)
+	// InsertOmitDefaults prepares an insert into the table without the columns that have a default value and return
+	// insert stmt. columns are the columns with a default value that are inserted too.
+	InsertOmitDefaults(
+		ctx context.Context,
+		table chconn.TableName,
+		columns []string,
+		queryOptions *chconn.QueryOptions,
+	) (chconn.InsertStmt, error)
+	// InsertWithRetry inserts the blocks of the insert source with the query. Each block is inserted with its own insert
+	// query and the insert_deduplication_token setting of the block is the base token (the token of the settings or a
+	// random token) and the index of the block (e.g. "token_0"). After the network errors only the block that failed
+	// is sent again with another connection, so the server does not insert the block again if a failed try was
+	// inserted. It needs a table with deduplication (e.g. ReplicatedMergeTree).
+	// NOTE: if it returns an error, the blocks before the block that failed are inserted
+	InsertWithRetry(ctx context.Context, query string, queryOptions *chconn.QueryOptions, source InsertSource) error
 	// Ping sends a ping to check that the connection to the server is alive.
 	Ping(ctx context.Context) error
+	// TablesStatus get the replication status of the tables (e.g. the delay of the replica).
+	// The tables that do not exist on the server are not in the result
+	TablesStatus(ctx context.Context, tables []chconn.TableName) (map[chconn.TableName]chconn.TableStatus, error)
 	Stat() *Stat
 }
 type pool struct {
@@ -104,6 +137,8 @@ type pool struct {
 	maxConnLifetime   time.Duration
 	maxConnIdleTime   time.Duration
 	healthCheckPeriod time.Duration
+	insertMaxRetries  int
+	insertRetryDelay  time.Duration
 
 	closeOnce sync.Once
 	closeChan chan struct{}
@@ -145,6 +180,12 @@ type Config struct {
 	// HealthCheckPeriod is the duration between checks of the health of idle connections.
 	HealthCheckPeriod time.Duration
 
+	// InsertMaxRetries is the maximum number of retries of InsertWithRetry after the network errors.
+	InsertMaxRetries int
+
+	// InsertRetryDelay is the duration between the retries of InsertWithRetry.
+	InsertRetryDelay time.Duration
+
 	// If set to true, pool doesn't do any I/O operation on initialization.
 	// And connects to the server only when the pool starts to be used.
 	// The default is false.
@@ -195,6 +236,8 @@ func ConnectConfig(ctx context.Context, config *Config) (Pool, error) {
 		maxConnLifetime:   config.MaxConnLifetime,
 		maxConnIdleTime:   config.MaxConnIdleTime,
 		healthCheckPeriod: config.HealthCheckPeriod,
+		insertMaxRetries:  config.InsertMaxRetries,
+		insertRetryDelay:  config.InsertRetryDelay,
 		closeChan:         make(chan struct{}),
 	}
 
@@ -260,6 +303,8 @@ func ConnectConfig(ctx context.Context, config *Config) (Pool, error) {
 // pool_max_conn_lifetime: duration string
 // pool_max_conn_idle_time: duration string
 // pool_health_check_period: duration string
+// pool_insert_max_retries: integer 0 or greater
+// pool_insert_retry_delay: duration string
 //
 // See Config for definitions of these arguments.
 //
@@ -341,6 +386,32 @@ func ParseConfig(connString string) (*Config, error) {
 		config.HealthCheckPeriod = defaultHealthCheckPeriod
 	}
 
+	if s, ok := config.ConnConfig.RuntimeParams["pool_insert_max_retries"]; ok {
+		delete(config.ConnConfig.RuntimeParams, "pool_insert_max_retries")
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse pool_insert_max_retries: %w", err)
+		}
+		if n < 0 {
+			//nolint:goerr113
+			return nil, fmt.Errorf("pool_insert_max_retries too small: %d", n)
+		}
+		config.InsertMaxRetries = int(n)
+	} else {
+		config.InsertMaxRetries = defaultInsertMaxRetries
+	}
+
+	if s, ok := config.ConnConfig.RuntimeParams["pool_insert_retry_delay"]; ok {
+		delete(config.ConnConfig.RuntimeParams, "pool_insert_retry_delay")
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool_insert_retry_delay: %w", err)
+		}
+		config.InsertRetryDelay = d
+	} else {
+		config.InsertRetryDelay = defaultInsertRetryDelay
+	}
+
 	return config, nil
 }
 
@@ -447,12 +518,18 @@ func (p *pool) Stat() *Stat {
 	return &Stat{s: p.p.Stat()}
 }
 
-func (p *pool) Exec(ctx context.Context, sql string) (interface{}, error) {
-	return p.ExecCallback(ctx, sql, nil, "", nil)
+func (p *pool) Exec(ctx context.Context, sql string) (*chconn.ExecResult, error) {
+	return p.ExecWithOption(ctx, sql, &chconn.QueryOptions{})
 }
 
-func (p *pool) ExecWithSetting(ctx context.Context, sql string, settings *setting.Settings) (interface{}, error) {
-	return p.ExecCallback(ctx, sql, settings, "", nil)
+func (p *pool) ExecWithSetting(
+	ctx context.Context,
+	sql string,
+	settings *setting.Settings,
+) (*chconn.ExecResult, error) {
+	return p.ExecWithOption(ctx, sql, &chconn.QueryOptions{
+		Settings: settings,
+	})
 }
 
 func (p *pool) ExecCallback(
@@ -460,13 +537,25 @@ func (p *pool) ExecCallback(
 	sql string,
 	settings *setting.Settings,
 	queryID string,
-	onProgress func(*chconn.Progress)) (interface{}, error) {
+	onProgress func(*chconn.Progress)) (*chconn.ExecResult, error) {
+	return p.ExecWithOption(ctx, sql, &chconn.QueryOptions{
+		QueryID:    queryID,
+		Settings:   settings,
+		OnProgress: onProgress,
+	})
+}
+
+func (p *pool) ExecWithOption(
+	ctx context.Context,
+	sql string,
+	queryOptions *chconn.QueryOptions,
+) (*chconn.ExecResult, error) {
 	for {
 		c, err := p.Acquire(ctx)
 		if err != nil {
 			return nil, err
 		}
-		res, err := c.ExecCallback(ctx, sql, settings, queryID, onProgress)
+		res, err := c.ExecWithOption(ctx, sql, queryOptions)
 		c.Release()
 		if errors.Is(err, syscall.EPIPE) {
 			continue
@@ -476,11 +565,13 @@ func (p *pool) ExecCallback(
 }
 
 func (p *pool) Select(ctx context.Context, query string) (chconn.SelectStmt, error) {
-	return p.SelectCallback(ctx, query, nil, "", nil, nil)
+	return p.SelectWithOption(ctx, query, &chconn.QueryOptions{})
 }
 
 func (p *pool) SelectWithSetting(ctx context.Context, query string, settings *setting.Settings) (chconn.SelectStmt, error) {
-	return p.SelectCallback(ctx, query, settings, "", nil, nil)
+	return p.SelectWithOption(ctx, query, &chconn.QueryOptions{
+		Settings: settings,
+	})
 }
 
 func (p *pool) SelectCallback(
@@ -490,6 +581,19 @@ func (p *pool) SelectCallback(
 	queryID string,
 	onProgress func(*chconn.Progress),
 	onProfile func(*chconn.Profile),
+) (chconn.SelectStmt, error) {
+	return p.SelectWithOption(ctx, query, &chconn.QueryOptions{
+		QueryID:    queryID,
+		Settings:   settings,
+		OnProgress: onProgress,
+		OnProfile:  onProfile,
+	})
+}
+
+func (p *pool) SelectWithOption(
+	ctx context.Context,
+	query string,
+	queryOptions *chconn.QueryOptions,
 ) (chconn.SelectStmt, error) {
 	for {
 		c, err := p.Acquire(ctx)
@@ -497,7 +601,7 @@ func (p *pool) SelectCallback(
 			return nil, err
 		}
 
-		s, err := c.SelectCallback(ctx, query, settings, queryID, onProgress, onProfile)
+		s, err := c.SelectWithOption(ctx, query, queryOptions)
 		if err != nil {
 			c.Release()
 			if errors.Is(err, syscall.EPIPE) {
@@ -510,17 +614,24 @@ func (p *pool) SelectCallback(
 }
 
 func (p *pool) Insert(ctx context.Context, query string) (chconn.InsertStmt, error) {
-	return p.InsertWithSetting(ctx, query, nil, "")
+	return p.InsertWithOption(ctx, query, &chconn.QueryOptions{})
 }
 
 func (p *pool) InsertWithSetting(ctx context.Context, query string, settings *setting.Settings, queryID string) (chconn.InsertStmt, error) {
+	return p.InsertWithOption(ctx, query, &chconn.QueryOptions{
+		QueryID:  queryID,
+		Settings: settings,
+	})
+}
+
+func (p *pool) InsertWithOption(ctx context.Context, query string, queryOptions *chconn.QueryOptions) (chconn.InsertStmt, error) {
 	for {
 		c, err := p.Acquire(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		s, err := c.InsertWithSetting(ctx, query, settings, queryID)
+		s, err := c.InsertWithOption(ctx, query, queryOptions)
 		if err != nil {
 			c.Release()
 			if errors.Is(err, syscall.EPIPE) {
@@ -533,6 +644,146 @@ func (p *pool) InsertWithSetting(ctx context.Context, query string, settings *se
 	}
 }
 
+func (p *pool) InsertOmitDefaults(
+	ctx context.Context,
+	table chconn.TableName,
+	columns []string,
+	queryOptions *chconn.QueryOptions,
+) (chconn.InsertStmt, error) {
+	for {
+		c, err := p.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := c.InsertOmitDefaults(ctx, table, columns, queryOptions)
+		if err != nil {
+			c.Release()
+			if errors.Is(err, syscall.EPIPE) {
+				continue
+			}
+			return nil, err
+		}
+
+		return s, nil
+	}
+}
+
+// InsertSource returns the columns of the block with the index of InsertWithRetry or no columns after the last block.
+// The indexes are requested in order and the columns are sent again if the insert of the block is retried, so they
+// must not be changed until the next index is requested.
+type InsertSource func(index int) ([]column.Column, error)
+
+// InsertBlocks returns the insert source of the blocks that are in memory.
+func InsertBlocks(blocks ...[]column.Column) InsertSource {
+	return func(index int) ([]column.Column, error) {
+		if index >= len(blocks) {
+			return nil, nil
+		}
+		return blocks[index], nil
+	}
+}
+
+func (p *pool) InsertWithRetry(
+	ctx context.Context,
+	query string,
+	queryOptions *chconn.QueryOptions,
+	source InsertSource,
+) error {
+	return insertBlocksWithRetry(ctx, queryOptions, source, p.insertMaxRetries, p.insertRetryDelay,
+		func(ctx context.Context, queryOptions *chconn.QueryOptions, columns []column.Column) error {
+			return p.insert(ctx, query, queryOptions, columns...)
+		})
+}
+
+// insertBlocksWithRetry inserts each block of the source with insert and retries the block that fails with a network
+// error up to maxRetries times.
+func insertBlocksWithRetry(
+	ctx context.Context,
+	queryOptions *chconn.QueryOptions,
+	source InsertSource,
+	maxRetries int,
+	retryDelay time.Duration,
+	insert func(ctx context.Context, queryOptions *chconn.QueryOptions, columns []column.Column) error,
+) error {
+	var options chconn.QueryOptions
+	if queryOptions != nil {
+		options = *queryOptions
+	}
+	settings := options.Settings
+	if settings == nil {
+		settings = setting.NewSettings()
+	}
+	token := uuid.New().String()
+	if v, ok := settings.Get("insert_deduplication_token"); ok {
+		if baseToken, ok := v.(string); ok && baseToken != "" {
+			token = baseToken
+		}
+	}
+
+	for index := 0; ; index++ {
+		columns, err := source(index)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+		options.Settings = settings.Clone()
+		options.Settings.InsertDeduplicationToken(token + "_" + strconv.Itoa(index))
+		for retry := 0; ; retry++ {
+			err = insert(ctx, &options, columns)
+			if err == nil || ctx.Err() != nil || !isNetworkError(err) || retry >= maxRetries {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryDelay):
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *pool) insert(ctx context.Context, query string, queryOptions *chconn.QueryOptions, columns ...column.Column) error {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	s, err := c.InsertWithOption(ctx, query, queryOptions)
+	if err != nil {
+		c.Release()
+		return err
+	}
+	return s.Commit(ctx, columns...)
+}
+
+// isNetworkError reports if the error is an error of the connection that the insert can be retried after it.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// TablesStatus acquires a connection from the Pool and get the replication status of the tables
+func (p *pool) TablesStatus(
+	ctx context.Context,
+	tables []chconn.TableName,
+) (map[chconn.TableName]chconn.TableStatus, error) {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+	return c.TablesStatus(ctx, tables)
+}
+
 // Ping acquires a connection from the Pool and send ping
 // If returns without error, the database Ping is considered successful, otherwise, the error is returned.
 func (p *pool) Ping(ctx context.Context) error {
@@ -1,11 +1,16 @@
 package chpool
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"runtime"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -13,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/vahid-sohrabloo/chconn"
 	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/setting"
 )
 
 func TestConnect(t *testing.T) {
@@ -30,19 +36,25 @@ func TestParseConfigExtractsPoolArguments(t *testing.T) {
 								pool_min_conns=1
 								pool_max_conn_lifetime=30s
 								pool_max_conn_idle_time=31s
-								pool_health_check_period=32s`)
+								pool_health_check_period=32s
+								pool_insert_max_retries=5
+								pool_insert_retry_delay=33s`)
 	assert.NoError(t, err)
 	assert.EqualValues(t, 42, config.MaxConns)
 	assert.EqualValues(t, 42, config.MaxConns)
 	assert.EqualValues(t, time.Second*30, config.MaxConnLifetime)
 	assert.EqualValues(t, time.Second*31, config.MaxConnIdleTime)
 	assert.EqualValues(t, time.Second*32, config.HealthCheckPeriod)
+	assert.EqualValues(t, 5, config.InsertMaxRetries)
+	assert.EqualValues(t, time.Second*33, config.InsertRetryDelay)
 
 	assert.NotContains(t, config.ConnConfig.RuntimeParams, "pool_max_conns")
 	assert.NotContains(t, config.ConnConfig.RuntimeParams, "pool_min_conns")
 	assert.NotContains(t, config.ConnConfig.RuntimeParams, "pool_max_conn_lifetime")
 	assert.NotContains(t, config.ConnConfig.RuntimeParams, "pool_max_conn_idle_time")
 	assert.NotContains(t, config.ConnConfig.RuntimeParams, "pool_health_check_period")
+	assert.NotContains(t, config.ConnConfig.RuntimeParams, "pool_insert_max_retries")
+	assert.NotContains(t, config.ConnConfig.RuntimeParams, "pool_insert_retry_delay")
 }
 
 func TestConnectCancel(t *testing.T) {
@@ -417,7 +429,7 @@ func TestPoolExecError(t *testing.T) {
 	if assert.Error(t, err) {
 		assert.Equal(t, "acquire: closed pool", err.Error())
 	}
-	assert.EqualValues(t, nil, results)
+	assert.Nil(t, results)
 }
 
 func TestPoolSelect(t *testing.T) {
@@ -525,13 +537,13 @@ func TestPoolInsert(t *testing.T) {
 
 	res, err := pool.Exec(context.Background(), `DROP TABLE IF EXISTS clickhouse_test_insert_pool`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 	res, err = pool.Exec(context.Background(), `CREATE TABLE clickhouse_test_insert_pool (
 				int8  Int8
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	insertStmt, err := pool.Insert(context.Background(), `INSERT INTO clickhouse_test_insert_pool (
 				int8
@@ -572,6 +584,37 @@ func TestPoolInsert(t *testing.T) {
 	assert.EqualValues(t, 1, stats.TotalConns())
 }
 
+func TestPoolInsertClose(t *testing.T) {
+	t.Parallel()
+
+	pool, err := Connect(context.Background(), os.Getenv("CHX_TEST_TCP_CONN_STRING"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(context.Background(), `DROP TABLE IF EXISTS clickhouse_test_insert_pool_close`)
+	require.NoError(t, err)
+	_, err = pool.Exec(context.Background(), `CREATE TABLE clickhouse_test_insert_pool_close (
+				int8  Int8
+			) Engine=Memory`)
+	require.NoError(t, err)
+
+	insertStmt, err := pool.Insert(context.Background(), `INSERT INTO clickhouse_test_insert_pool_close (
+				int8
+			) VALUES`)
+	require.NoError(t, err)
+	col := column.NewInt8(false)
+	col.Append(1)
+	require.NoError(t, insertStmt.Write(context.Background(), col))
+	assert.EqualValues(t, 1, pool.Stat().AcquiredConns())
+
+	// the insert is ended after the partial write and the connection is released to the pool
+	require.NoError(t, insertStmt.Close(context.Background()))
+	waitForReleaseToComplete()
+	stats := pool.Stat()
+	assert.EqualValues(t, 0, stats.AcquiredConns())
+	assert.EqualValues(t, 1, stats.TotalConns())
+}
+
 func TestPoolInsertError(t *testing.T) {
 	t.Parallel()
 
@@ -599,6 +642,168 @@ func TestPoolInsertError(t *testing.T) {
 	require.Nil(t, insertStmt)
 }
 
+// failOnceWriter fails the first write that contains the marker with a network error. Half of the write is sent, like
+// a connection that is dropped in the middle of the data
+type failOnceWriter struct {
+	w      io.Writer
+	marker []byte
+	failed *int32
+}
+
+func (w *failOnceWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, w.marker) && atomic.CompareAndSwapInt32(w.failed, 0, 1) {
+		n, _ := w.w.Write(p[:len(p)/2])
+		return n, &net.OpError{Op: "write", Net: "tcp", Err: syscall.ECONNRESET}
+	}
+	return w.w.Write(p)
+}
+
+func TestPoolInsertWithRetry(t *testing.T) {
+	t.Parallel()
+
+	config, err := ParseConfig(os.Getenv("CHX_TEST_TCP_CONN_STRING"))
+	require.NoError(t, err)
+	config.InsertRetryDelay = time.Millisecond
+	config.ConnConfig.Compress = false
+	var failed int32
+	config.ConnConfig.WriterFunc = func(w io.Writer) io.Writer {
+		return &failOnceWriter{
+			w: w,
+			// the data of the column of the second block, so the connection is dropped after the insert query and
+			// the block header are sent
+			marker: []byte{11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+			failed: &failed,
+		}
+	}
+	atomic.StoreInt32(&failed, 1)
+	pool, err := ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(context.Background(), `DROP TABLE IF EXISTS clickhouse_test_insert_retry_pool`)
+	require.NoError(t, err)
+	_, err = pool.Exec(context.Background(), `CREATE TABLE clickhouse_test_insert_retry_pool (
+				int8  Int8
+			) Engine=MergeTree ORDER BY int8 SETTINGS non_replicated_deduplication_window = 100`)
+	require.NoError(t, err)
+
+	countRows := func() []uint64 {
+		selectStmt, err := pool.Select(context.Background(), `SELECT count() FROM clickhouse_test_insert_retry_pool`)
+		require.NoError(t, err)
+		colCount := column.NewUint64(false)
+		var count []uint64
+		for selectStmt.Next() {
+			require.NoError(t, selectStmt.NextColumn(colCount))
+			colCount.ReadAll(&count)
+		}
+		require.NoError(t, selectStmt.Err())
+		selectStmt.Close()
+		return count
+	}
+
+	col1 := column.NewInt8(false)
+	col2 := column.NewInt8(false)
+	for i := 1; i <= 10; i++ {
+		col1.Append(int8(i))
+		col2.Append(int8(i + 10))
+	}
+	atomic.StoreInt32(&failed, 0)
+	err = pool.InsertWithRetry(context.Background(), `INSERT INTO clickhouse_test_insert_retry_pool (
+				int8
+			) VALUES`, &chconn.QueryOptions{}, InsertBlocks([]column.Column{col1}, []column.Column{col2}))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&failed))
+	// the data of the dropped insert is not inserted and the retry inserts one copy of the second block
+	assert.Equal(t, []uint64{20}, countRows())
+
+	// the same token is deduplicated for each block
+	settings := setting.NewSettings()
+	settings.InsertDeduplicationToken("clickhouse_test_insert_retry_pool_token")
+	for i := 0; i < 2; i++ {
+		err = pool.InsertWithRetry(context.Background(), `INSERT INTO clickhouse_test_insert_retry_pool (
+				int8
+			) VALUES`, &chconn.QueryOptions{Settings: settings}, InsertBlocks([]column.Column{col1}, []column.Column{col2}))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []uint64{40}, countRows())
+}
+
+func TestInsertBlocksWithRetry(t *testing.T) {
+	t.Parallel()
+
+	settings := setting.NewSettings()
+	settings.InsertDeduplicationToken("token")
+	blocks := []column.Column{column.NewInt8(false), column.NewInt8(false), column.NewInt8(false)}
+	var requested []int
+	source := func(index int) ([]column.Column, error) {
+		requested = append(requested, index)
+		if index >= len(blocks) {
+			return nil, nil
+		}
+		return []column.Column{blocks[index]}, nil
+	}
+	var tokens []string
+	var sent []column.Column
+	fails := 2
+	err := insertBlocksWithRetry(context.Background(), &chconn.QueryOptions{Settings: settings}, source, 3, 0,
+		func(ctx context.Context, queryOptions *chconn.QueryOptions, columns []column.Column) error {
+			token, _ := queryOptions.Settings.Get("insert_deduplication_token")
+			tokens = append(tokens, token.(string))
+			sent = append(sent, columns[0])
+			// the second block fails twice
+			if len(sent) > 1 && fails > 0 {
+				fails--
+				return &net.OpError{Op: "write", Net: "tcp", Err: syscall.ECONNRESET}
+			}
+			return nil
+		})
+	require.NoError(t, err)
+	// only the block that failed is sent again with the same token
+	assert.Equal(t, []string{"token_0", "token_1", "token_1", "token_1", "token_2"}, tokens)
+	assert.Equal(t, []column.Column{blocks[0], blocks[1], blocks[1], blocks[1], blocks[2]}, sent)
+	assert.Equal(t, []int{0, 1, 2, 3}, requested)
+	// the settings of the caller are not changed
+	token, _ := settings.Get("insert_deduplication_token")
+	assert.Equal(t, "token", token)
+
+	// the retries are limited for each block
+	fails = 10
+	sent = nil
+	requested = nil
+	err = insertBlocksWithRetry(context.Background(), nil, source, 2, 0,
+		func(ctx context.Context, queryOptions *chconn.QueryOptions, columns []column.Column) error {
+			sent = append(sent, columns[0])
+			if len(sent) > 1 {
+				return &net.OpError{Op: "write", Net: "tcp", Err: syscall.ECONNRESET}
+			}
+			return nil
+		})
+	require.Error(t, err)
+	assert.Len(t, sent, 4)
+	assert.Equal(t, []int{0, 1}, requested)
+
+	// the other errors are not retried
+	sent = nil
+	err = insertBlocksWithRetry(context.Background(), nil, source, 2, 0,
+		func(ctx context.Context, queryOptions *chconn.QueryOptions, columns []column.Column) error {
+			sent = append(sent, columns[0])
+			return &chconn.ChError{Code: 60}
+		})
+	require.Error(t, err)
+	assert.Len(t, sent, 1)
+}
+
+func TestIsNetworkError(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isNetworkError(fmt.Errorf("write: %w", &net.OpError{Op: "write", Err: syscall.ECONNRESET})))
+	assert.True(t, isNetworkError(fmt.Errorf("read: %w", io.EOF)))
+	assert.True(t, isNetworkError(syscall.EPIPE))
+	assert.False(t, isNetworkError(&chconn.ChError{Code: 60}))
+	assert.False(t, isNetworkError(chconn.ErrInsertMinColumn))
+}
+
 func TestConnReleaseClosesConnInFailedTransaction(t *testing.T) {
 	t.Parallel()
 
@@ -706,6 +911,18 @@ func TestParseConfigError(t *testing.T) {
 			name:       "invalid pool_health_check_period",
 			connString: "pool_health_check_period=invalid",
 			err:        "invalid pool_health_check_period: time: invalid duration \"invalid\"",
+		}, {
+			name:       "invalid pool_insert_max_retries",
+			connString: "pool_insert_max_retries=invalid",
+			err:        "cannot parse pool_insert_max_retries: strconv.ParseInt: parsing \"invalid\": invalid syntax",
+		}, {
+			name:       "low pool_insert_max_retries",
+			connString: "pool_insert_max_retries=-1",
+			err:        "pool_insert_max_retries too small: -1",
+		}, {
+			name:       "invalid pool_insert_retry_delay",
+			connString: "pool_insert_retry_delay=invalid",
+			err:        "invalid pool_insert_retry_delay: time: invalid duration \"invalid\"",
 		},
 	}
 
@@ -22,18 +22,32 @@ type ClientInfo struct {
 	ClientRevision     uint64
 
 	QuotaKey string
+
+	// TraceContext is the OpenTelemetry trace context of the query
+	TraceContext *TraceContext
+
+	// secondaryQuery is true if the query is sent by a server of the cluster on behalf of InitialUser
+	secondaryQuery bool
 }
 
 // Write Only values that are not calculated automatically or passed separately are serialized.
 // Revisions are passed to use format that server will understand or client was used.
 func (c *ClientInfo) write(ch *conn) {
-	// InitialQuery
-	ch.writer.Uint8(1)
+	// query kind
+	if c.secondaryQuery {
+		ch.writer.Uint8(2)
+	} else {
+		ch.writer.Uint8(1)
+	}
 
 	ch.writer.String(c.InitialUser)
 	ch.writer.String(c.InitialQueryID)
 
 	ch.writer.String("[::ffff:127.0.0.1]:0")
+	if ch.serverInfo.Revision >= dbmsMinProtocolVersionWithInitialQueryStartTime {
+		// initial query start time in microseconds. the server sets it for the initial query
+		ch.writer.Int64(0)
+	}
 	// iface type
 	ch.writer.Uint8(1) // tcp
 	ch.writer.String(c.OSUser)
@@ -45,14 +59,25 @@ func (c *ClientInfo) write(ch *conn) {
 	if ch.serverInfo.Revision >= dbmsMinRevisionWithQuotaKeyInClientInfo {
 		ch.writer.String(c.QuotaKey)
 	}
+	if ch.serverInfo.Revision >= dbmsMinProtocolVersionWithDistributedDepth {
+		// distributed depth
+		ch.writer.Uvarint(0)
+	}
 	if ch.serverInfo.Revision >= dbmsMinRevisionWithVersionPatch {
 		ch.writer.Uvarint(c.ClientVersionPatch)
 	}
 
 	if ch.serverInfo.Revision >= dbmsMinRevisionWithOpentelemetry {
-		// TODO Support Opentelemetry later
-		// Don't have OpenTelemetry header.
-		ch.writer.Uint8(0)
+		c.TraceContext.write(ch)
+	}
+
+	if ch.serverInfo.Revision >= dbmsMinRevisionWithParallelReplicas {
+		// collaborate with initiator
+		ch.writer.Uvarint(0)
+		// count participating replicas
+		ch.writer.Uvarint(0)
+		// number of current replica
+		ch.writer.Uvarint(0)
 	}
 }
 
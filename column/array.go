@@ -27,7 +27,7 @@ func NewArray(subColumn Column) *Array {
 }
 
 func (c *Array) ReadRaw(num int, r *readerwriter.Reader) error {
-	c.Reset()
+	c.reset()
 	err := c.Uint64.ReadRaw(num, r)
 	if err != nil {
 		return err
@@ -46,7 +46,13 @@ func (c *Array) HeaderReader(r *readerwriter.Reader) error {
 	return c.subColumn.HeaderReader(r)
 }
 
+// Reset the array and the sub column for the next insert block
 func (c *Array) Reset() {
+	c.reset()
+	c.subColumn.Reset()
+}
+
+func (c *Array) reset() {
 	c.Uint64.Reset()
 	c.offset = 0
 }
@@ -23,7 +23,7 @@ func TestDate32LC(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_lc_date32`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 	settings := setting.NewSettings()
 	settings.AllowSuspiciousLowCardinalityTypes(true)
 	res, err = conn.ExecWithSetting(context.Background(), `CREATE TABLE test_lc_date32 (
@@ -34,7 +34,7 @@ func TestDate32LC(t *testing.T) {
 			) Engine=Memory`, settings)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewDate32(false)
 	colLC := column.NewLC(col)
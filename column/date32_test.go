@@ -22,7 +22,7 @@ func TestDate32(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_date32`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_date32 (
 				date32 Date32,
@@ -32,7 +32,7 @@ func TestDate32(t *testing.T) {
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewDate32(false)
 
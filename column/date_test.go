@@ -22,7 +22,7 @@ func TestDate(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_date`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_date (
 				date Date,
@@ -32,7 +32,7 @@ func TestDate(t *testing.T) {
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewDate(false)
 
@@ -22,7 +22,7 @@ func TestDateTime64(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_datetime64`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_datetime64 (
 				datetime64 DateTime64,
@@ -32,7 +32,7 @@ func TestDateTime64(t *testing.T) {
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewDateTime64(3, false)
 
@@ -23,7 +23,7 @@ func TestDateTimeLC(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_lc_datetime`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 	settings := setting.NewSettings()
 	settings.AllowSuspiciousLowCardinalityTypes(true)
 	res, err = conn.ExecWithSetting(context.Background(), `CREATE TABLE test_lc_datetime (
@@ -34,7 +34,7 @@ func TestDateTimeLC(t *testing.T) {
 			) Engine=Memory`, settings)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewDateTime(false)
 	colLC := column.NewLC(col)
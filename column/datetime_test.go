@@ -22,7 +22,7 @@ func TestDateTime(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_datetime`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_datetime (
 				datetime DateTime,
@@ -32,7 +32,7 @@ func TestDateTime(t *testing.T) {
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewDateTime(false)
 
@@ -21,7 +21,7 @@ func TestDecimal128(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_decimal128`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_decimal128 (
 				decimal128 Decimal128(3),
@@ -31,7 +31,7 @@ func TestDecimal128(t *testing.T) {
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewDecimal128(false)
 
@@ -21,7 +21,7 @@ func TestDecimal32(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_decimal32`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_decimal32 (
 				decimal32 Decimal32(3),
@@ -31,7 +31,7 @@ func TestDecimal32(t *testing.T) {
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewDecimal32(3, false)
 
@@ -22,7 +22,7 @@ func TestFixedStringLC(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_lc_fixedstring`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_lc_fixedstring (
 				fixed_lc LowCardinality(FixedString(10)),
@@ -32,7 +32,7 @@ func TestFixedStringLC(t *testing.T) {
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewRaw(10, false)
 	colLC := column.NewLC(col)
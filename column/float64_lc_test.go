@@ -22,7 +22,7 @@ func TestFloat64LC(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_lc_float64`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 	settings := setting.NewSettings()
 	settings.AllowSuspiciousLowCardinalityTypes(true)
 	res, err = conn.ExecWithSetting(context.Background(), `CREATE TABLE test_lc_float64 (
@@ -33,7 +33,7 @@ func TestFloat64LC(t *testing.T) {
 			) Engine=Memory`, settings)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewFloat64(false)
 	colLC := column.NewLC(col)
@@ -189,5 +189,10 @@ func (c *LC) setNullable(nullable bool) {
 func (c *LC) AppendEmpty() {
 }
 
+// Reset the dictionary for the next insert block
 func (c *LC) Reset() {
+	c.dictColumn.Reset()
+	if c.dictColumn.isNullable() {
+		c.dictColumn.AppendEmpty()
+	}
 }
@@ -57,6 +57,13 @@ func (c *Map) HeaderReader(r *readerwriter.Reader) error {
 	return c.columnValue.HeaderReader(r)
 }
 
+// Reset the map and the key and value columns for the next insert block
+func (c *Map) Reset() {
+	c.reset()
+	c.columnKey.Reset()
+	c.columnValue.Reset()
+}
+
 func (c *Map) reset() {
 	c.Uint64.Reset()
 	c.offset = 0
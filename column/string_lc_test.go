@@ -22,7 +22,7 @@ func TestStringLC(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_lc_string`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_lc_string (
 				string_lc LowCardinality(String),
@@ -32,7 +32,7 @@ func TestStringLC(t *testing.T) {
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewString(false)
 	colLC := column.NewLC(col)
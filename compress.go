@@ -0,0 +1,61 @@
+package chconn
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+)
+
+// defaultLZ4HCLevel is the default level of LZ4HC in ClickHouse
+const defaultLZ4HCLevel = 9
+
+// newCompressWriter wraps the writer with the compression method of the config.
+func newCompressWriter(w io.Writer, config *Config) io.Writer {
+	var method byte = readerwriter.LZ4
+	level := config.CompressLevel
+	switch config.CompressMethod {
+	case CompressLZ4HC:
+		if level == 0 {
+			level = defaultLZ4HCLevel
+		}
+	case CompressZSTD:
+		method = readerwriter.ZSTD
+	case CompressNone:
+		method = readerwriter.NONE
+	default:
+		// the level of LZ4 enables the high compression mode
+		level = 0
+	}
+	return readerwriter.NewCompressWriter(w, method, level, config.CompressBlockSize)
+}
+
+// writeCompressSettings writes the settings that the server uses to compress the data it sends
+// (network_compression_method and network_zstd_compression_level). LZ4 is the default of the server.
+func writeCompressSettings(w *readerwriter.Writer, config *Config, asString bool) {
+	if config.CompressMethod != CompressZSTD && config.CompressMethod != CompressNone {
+		return
+	}
+	writeStringSetting(w, "network_compression_method", string(config.CompressMethod), asString)
+	if config.CompressMethod == CompressZSTD && config.CompressLevel > 0 {
+		w.String("network_zstd_compression_level")
+		if asString {
+			// flags
+			w.Uvarint(0)
+			w.String(strconv.Itoa(config.CompressLevel))
+			return
+		}
+		w.Varint(int64(config.CompressLevel))
+	}
+}
+
+// writeStringSetting writes a setting that is a string in both formats (e.g. an enum). The flags are only written if
+// the settings are serialized as strings
+func writeStringSetting(w *readerwriter.Writer, name, value string, asString bool) {
+	w.String(name)
+	if asString {
+		// flags
+		w.Uvarint(0)
+	}
+	w.String(value)
+}
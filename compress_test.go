@@ -0,0 +1,121 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+
+	_, err = conn.Exec(context.Background(), `DROP TABLE IF EXISTS clickhouse_test_compress`)
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), `CREATE TABLE clickhouse_test_compress (
+				method String,
+				number UInt64
+			) Engine=Memory`)
+	require.NoError(t, err)
+	conn.Close(context.Background())
+
+	tests := []struct {
+		name      string
+		method    CompressMethod
+		level     int
+		blockSize int
+	}{
+		{name: "lz4", method: CompressLZ4},
+		{name: "lz4hc", method: CompressLZ4HC, level: 4},
+		{name: "zstd", method: CompressZSTD},
+		{name: "zstd level", method: CompressZSTD, level: 3},
+		{name: "none", method: CompressNone},
+		{name: "block size", method: CompressLZ4, blockSize: 1024},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := ParseConfig(connString)
+			require.NoError(t, err)
+			config.Compress = true
+			config.CompressMethod = tt.method
+			config.CompressLevel = tt.level
+			config.CompressBlockSize = tt.blockSize
+
+			conn, err := ConnectConfig(context.Background(), config)
+			require.NoError(t, err)
+			defer conn.Close(context.Background())
+
+			colMethod := column.NewString(false)
+			col := column.NewUint64(false)
+			rows := 10000
+			for i := 0; i < rows; i++ {
+				colMethod.AppendString(tt.name)
+				col.Append(uint64(i))
+			}
+			insertStmt, err := conn.Insert(context.Background(),
+				`INSERT INTO clickhouse_test_compress (method, number) VALUES`)
+			require.NoError(t, err)
+			require.NoError(t, insertStmt.Commit(context.Background(), colMethod, col))
+
+			stmt, err := conn.Select(context.Background(),
+				`SELECT number FROM clickhouse_test_compress WHERE method = '`+tt.name+`' ORDER BY number`)
+			require.NoError(t, err)
+			colRead := column.NewUint64(false)
+			var colData []uint64
+			for stmt.Next() {
+				require.NoError(t, stmt.NextColumn(colRead))
+				colRead.ReadAll(&colData)
+			}
+			require.NoError(t, stmt.Err())
+			stmt.Close()
+			require.Len(t, colData, rows)
+			require.Equal(t, uint64(rows-1), colData[rows-1])
+		})
+	}
+}
+
+func TestWriteCompressSettings(t *testing.T) {
+	t.Parallel()
+
+	w := readerwriter.NewWriter()
+	writeCompressSettings(w, &Config{CompressMethod: CompressLZ4HC}, true)
+	require.Zero(t, w.Output().Len())
+
+	writeCompressSettings(w, &Config{CompressMethod: CompressZSTD, CompressLevel: 3}, true)
+	r := readerwriter.NewReader(w.Output())
+	for _, expected := range [][2]string{
+		{"network_compression_method", "zstd"},
+		{"network_zstd_compression_level", "3"},
+	} {
+		name, err := r.String()
+		require.NoError(t, err)
+		require.Equal(t, expected[0], name)
+		flags, err := r.Uvarint()
+		require.NoError(t, err)
+		require.Zero(t, flags)
+		value, err := r.String()
+		require.NoError(t, err)
+		require.Equal(t, expected[1], value)
+	}
+	require.Zero(t, w.Output().Len())
+
+	// the servers before the settings are serialized as strings get the binary values without the flags
+	writeCompressSettings(w, &Config{CompressMethod: CompressZSTD, CompressLevel: 3}, false)
+	expected := readerwriter.NewWriter()
+	expected.String("network_compression_method")
+	expected.String("zstd")
+	expected.String("network_zstd_compression_level")
+	expected.Varint(3)
+	require.Equal(t, expected.Output().Bytes(), w.Output().Bytes())
+}
@@ -19,6 +19,25 @@ const defaultUsername = "default"
 const defaultDatabase = "default"
 const defaultDBPort = "9000"
 const defaultClientName = "chx"
+const defaultCancelTimeout = 5 * time.Second
+const defaultCloseDrainTimeout = 5 * time.Second
+const defaultKeepAlive = 5 * time.Minute
+const defaultCloseDrainMaxBytes = 10 << 20
+
+// CompressMethod is the compression method of the data blocks
+type CompressMethod string
+
+const (
+	// CompressLZ4 is the default compression method
+	CompressLZ4 CompressMethod = "lz4"
+	// CompressLZ4HC is LZ4 with the high compression mode. It has a better compression ratio than LZ4 but the
+	// compression is slower. The data is decompressed as LZ4, so the server only compresses its data with LZ4
+	CompressLZ4HC CompressMethod = "lz4hc"
+	// CompressZSTD has a better compression ratio than LZ4 but it needs more CPU
+	CompressZSTD CompressMethod = "zstd"
+	// CompressNone sends the data in the frames of the compression without compressing it
+	CompressNone CompressMethod = "none"
+)
 
 type AfterConnectFunc func(ctx context.Context, conn Conn) error
 type ValidateConnectFunc func(ctx context.Context, conn Conn) error
@@ -34,11 +53,52 @@ type Config struct {
 	ClientName     string
 	TLSConfig      *tls.Config // nil disables TLS
 	ConnectTimeout time.Duration
+	// ReadTimeout and WriteTimeout are the max time that a read from or a write to the connection can be blocked (e.g.
+	// waiting for the next packet of a long-running query). Zero is no timeout
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// KeepAlive is the period of the TCP keep-alive probes. Zero disables the keep-alive probes
+	KeepAlive time.Duration
+	// TCPUserTimeout is the max time that the sent data can remain unacknowledged before the connection is closed
+	// (TCP_USER_TIMEOUT). It detects the half-open connections faster than the keep-alive probes. It is only supported
+	// on linux. Zero is the default of the system
+	TCPUserTimeout time.Duration
 	DialFunc       DialFunc   // e.g. net.Dialer.DialContext
 	LookupFunc     LookupFunc // e.g. net.Resolver.LookupHost
 	ReaderFunc     ReaderFunc // e.g. bufio.Reader
 	Compress       bool
-	WriterFunc     WriterFunc
+	// CompressMethod is the compression method of the data blocks if Compress is enabled. Empty means LZ4
+	CompressMethod CompressMethod
+	// CompressLevel is the level of LZ4HC (1-9) or ZSTD (1-22). Zero is the default level of the method
+	CompressLevel int
+	// CompressBlockSize is the max size of the uncompressed data in each compressed frame. Zero is 1 MiB
+	CompressBlockSize int
+	// SkipCompressChecksum disables the verification of the checksums of the compressed data that is read from the
+	// server. It must only be used for benchmarking
+	SkipCompressChecksum bool
+	WriterFunc           WriterFunc
+	// CancelTimeout is the time to wait for the server to end a query after the context is canceled and the cancel
+	// packet is sent. If the server doesn't end the query in this time the connection is closed.
+	// Zero closes the connection immediately without sending the cancel packet.
+	CancelTimeout time.Duration
+	// CloseDrainTimeout is the time that SelectStmt.Close waits for the server to end an unfinished query after the
+	// cancel packet is sent. The rest of the result is read and discarded, so the connection can be used again.
+	// If the server doesn't end the query in this time the connection is closed. Zero always closes the connection.
+	CloseDrainTimeout time.Duration
+	// CloseDrainMaxBytes is the max number of bytes that SelectStmt.Close reads to discard an unfinished query before
+	// the connection is closed. Zero is no limit
+	CloseDrainMaxBytes int64
+	// DeadlineExecutionTime sets the max_execution_time setting of the queries from the deadline of the context (plus
+	// one second), so the server stops the queries that the client gave up. A smaller max_execution_time setting of
+	// the query is kept
+	DeadlineExecutionTime bool
+	// QuotaKey is the key of the quota that the server uses for the queries of this connection
+	QuotaKey string
+	// Cluster and ClusterSecret connect to the server as a server of the cluster with the <secret> of the cluster
+	// (like a Distributed table). The queries run on behalf of QueryOptions.InitialUser (User if it is empty) without
+	// the password of the user
+	Cluster       string
+	ClusterSecret string
 	// Run-time parameters to set on connection as session default values (e.g. search_path or application_name)
 	RuntimeParams map[string]string
 
@@ -130,6 +190,10 @@ func NetworkAddress(host string, port uint16) (network, address string) {
 //   CHPASSWORD
 //   CHCLIENTNAME
 //   CHCONNECT_TIMEOUT
+//   CHREAD_TIMEOUT
+//   CHWRITE_TIMEOUT
+//   CHKEEPALIVE
+//   CHTCP_USER_TIMEOUT
 //   CHSSLMODE
 //   CHSSLKEY
 //   CHSSLCERT
@@ -183,11 +247,71 @@ func ParseConfig(connString string) (*Config, error) {
 		Password:             settings["password"],
 		RuntimeParams:        make(map[string]string),
 		ClientName:           settings["client_name"],
+		QuotaKey:             settings["quota_key"],
+		Cluster:              settings["cluster"],
+		ClusterSecret:        settings["cluster_secret"],
 		connString:           connString,
 	}
 
-	if compress, err := strconv.ParseBool(settings["compress"]); err == nil && compress {
-		config.Compress = true
+	if compressSetting, present := settings["compress"]; present {
+		compress, method, err := parseCompressSetting(compressSetting)
+		if err != nil {
+			return nil, &parseConfigError{connString: connString, msg: "invalid compress", err: err}
+		}
+		config.Compress = compress
+		config.CompressMethod = method
+	}
+
+	if compressLevelSetting, present := settings["compress_level"]; present {
+		compressLevel, err := strconv.ParseUint(compressLevelSetting, 10, 8)
+		if err != nil {
+			return nil, &parseConfigError{connString: connString, msg: "invalid compress_level", err: err}
+		}
+		config.CompressLevel = int(compressLevel)
+	}
+
+	if compressBlockSizeSetting, present := settings["compress_block_size"]; present {
+		compressBlockSize, err := strconv.ParseUint(compressBlockSizeSetting, 10, 31)
+		if err != nil {
+			return nil, &parseConfigError{connString: connString, msg: "invalid compress_block_size", err: err}
+		}
+		config.CompressBlockSize = int(compressBlockSize)
+	}
+
+	if skipChecksumSetting, present := settings["skip_compress_checksum"]; present {
+		skipChecksum, err := strconv.ParseBool(skipChecksumSetting)
+		if err != nil {
+			return nil, &parseConfigError{connString: connString, msg: "invalid skip_compress_checksum", err: err}
+		}
+		config.SkipCompressChecksum = skipChecksum
+	}
+
+	if deadlineExecutionTimeSetting, present := settings["deadline_execution_time"]; present {
+		deadlineExecutionTime, err := strconv.ParseBool(deadlineExecutionTimeSetting)
+		if err != nil {
+			return nil, &parseConfigError{connString: connString, msg: "invalid deadline_execution_time", err: err}
+		}
+		config.DeadlineExecutionTime = deadlineExecutionTime
+	}
+
+	timeouts := []struct {
+		name    string
+		timeout *time.Duration
+	}{
+		{"read_timeout", &config.ReadTimeout},
+		{"write_timeout", &config.WriteTimeout},
+		{"keepalive", &config.KeepAlive},
+		{"tcp_user_timeout", &config.TCPUserTimeout},
+	}
+	config.KeepAlive = defaultKeepAlive
+	for _, t := range timeouts {
+		if timeoutSetting, present := settings[t.name]; present {
+			timeout, err := parseConnectTimeoutSetting(timeoutSetting)
+			if err != nil {
+				return nil, &parseConfigError{connString: connString, msg: "invalid " + t.name, err: err}
+			}
+			*t.timeout = timeout
+		}
 	}
 
 	if connectTimeoutSetting, present := settings["connect_timeout"]; present {
@@ -196,27 +320,68 @@ func ParseConfig(connString string) (*Config, error) {
 			return nil, &parseConfigError{connString: connString, msg: "invalid connect_timeout", err: err}
 		}
 		config.ConnectTimeout = connectTimeout
-		config.DialFunc = makeConnectTimeoutDialFunc(connectTimeout)
+		config.DialFunc = makeConnectTimeoutDialFunc(connectTimeout, config.KeepAlive)
 	} else {
-		defaultDialer := makeDefaultDialer()
+		defaultDialer := makeDefaultDialer(config.KeepAlive)
 		config.DialFunc = defaultDialer.DialContext
 	}
 
 	config.LookupFunc = makeDefaultResolver().LookupHost
 
+	config.CancelTimeout = defaultCancelTimeout
+	if cancelTimeoutSetting, present := settings["cancel_timeout"]; present {
+		cancelTimeout, err := parseConnectTimeoutSetting(cancelTimeoutSetting)
+		if err != nil {
+			return nil, &parseConfigError{connString: connString, msg: "invalid cancel_timeout", err: err}
+		}
+		config.CancelTimeout = cancelTimeout
+	}
+
+	config.CloseDrainTimeout = defaultCloseDrainTimeout
+	if closeDrainTimeoutSetting, present := settings["close_drain_timeout"]; present {
+		closeDrainTimeout, err := parseConnectTimeoutSetting(closeDrainTimeoutSetting)
+		if err != nil {
+			return nil, &parseConfigError{connString: connString, msg: "invalid close_drain_timeout", err: err}
+		}
+		config.CloseDrainTimeout = closeDrainTimeout
+	}
+
+	config.CloseDrainMaxBytes = defaultCloseDrainMaxBytes
+	if closeDrainMaxBytesSetting, present := settings["close_drain_max_bytes"]; present {
+		closeDrainMaxBytes, err := strconv.ParseUint(closeDrainMaxBytesSetting, 10, 63)
+		if err != nil {
+			return nil, &parseConfigError{connString: connString, msg: "invalid close_drain_max_bytes", err: err}
+		}
+		config.CloseDrainMaxBytes = int64(closeDrainMaxBytes)
+	}
+
 	notRuntimeParams := map[string]struct{}{
-		"host":            {},
-		"port":            {},
-		"database":        {},
-		"user":            {},
-		"password":        {},
-		"connect_timeout": {},
-		"sslmode":         {},
-		"client_name":     {},
-		"sslkey":          {},
-		"sslcert":         {},
-		"sslrootcert":     {},
-		"compress":        {},
+		"host":                    {},
+		"port":                    {},
+		"database":                {},
+		"user":                    {},
+		"password":                {},
+		"connect_timeout":         {},
+		"read_timeout":            {},
+		"write_timeout":           {},
+		"keepalive":               {},
+		"tcp_user_timeout":        {},
+		"cancel_timeout":          {},
+		"close_drain_timeout":     {},
+		"close_drain_max_bytes":   {},
+		"deadline_execution_time": {},
+		"quota_key":               {},
+		"cluster":                 {},
+		"cluster_secret":          {},
+		"sslmode":                 {},
+		"client_name":             {},
+		"sslkey":                  {},
+		"sslcert":                 {},
+		"sslrootcert":             {},
+		"compress":                {},
+		"compress_level":          {},
+		"compress_block_size":     {},
+		"skip_compress_checksum":  {},
 	}
 
 	for k, v := range settings {
@@ -297,17 +462,21 @@ func parseEnvSettings() map[string]string {
 	settings := make(map[string]string)
 
 	nameMap := map[string]string{
-		"CHHOST":            "host",
-		"CHPORT":            "port",
-		"CHDATABASE":        "database",
-		"CHUSER":            "user",
-		"CHPASSWORD":        "password",
-		"CHCLIENTNAME":      "client_name",
-		"CHCONNECT_TIMEOUT": "connect_timeout",
-		"CHSSLMODE":         "sslmode",
-		"CHSSLKEY":          "sslkey",
-		"CHSSLCERT":         "sslcert",
-		"CHSSLROOTCERT":     "sslrootcert",
+		"CHHOST":             "host",
+		"CHPORT":             "port",
+		"CHDATABASE":         "database",
+		"CHUSER":             "user",
+		"CHPASSWORD":         "password",
+		"CHCLIENTNAME":       "client_name",
+		"CHCONNECT_TIMEOUT":  "connect_timeout",
+		"CHREAD_TIMEOUT":     "read_timeout",
+		"CHWRITE_TIMEOUT":    "write_timeout",
+		"CHKEEPALIVE":        "keepalive",
+		"CHTCP_USER_TIMEOUT": "tcp_user_timeout",
+		"CHSSLMODE":          "sslmode",
+		"CHSSLKEY":           "sslkey",
+		"CHSSLCERT":          "sslcert",
+		"CHSSLROOTCERT":      "sslrootcert",
 	}
 
 	for envname, realname := range nameMap {
@@ -587,8 +756,13 @@ func parsePort(s string) (uint16, error) {
 	return uint16(port), nil
 }
 
-func makeDefaultDialer() *net.Dialer {
-	return &net.Dialer{KeepAlive: 5 * time.Minute}
+// makeDefaultDialer makes the dialer with the keep-alive period of the config. Zero disables the keep-alive probes.
+func makeDefaultDialer(keepAlive time.Duration) *net.Dialer {
+	if keepAlive == 0 {
+		// zero is the default period of net.Dialer
+		keepAlive = -1
+	}
+	return &net.Dialer{KeepAlive: keepAlive}
 }
 
 func makeDefaultResolver() *net.Resolver {
@@ -606,8 +780,22 @@ func parseConnectTimeoutSetting(s string) (time.Duration, error) {
 	return time.Duration(timeout) * time.Second, nil
 }
 
-func makeConnectTimeoutDialFunc(timeout time.Duration) DialFunc {
-	d := makeDefaultDialer()
+// parseCompressSetting parses the compress setting. It is a bool (LZ4) or the compression method
+// (lz4, lz4hc, zstd or none).
+func parseCompressSetting(s string) (bool, CompressMethod, error) {
+	switch method := CompressMethod(strings.ToLower(s)); method {
+	case CompressLZ4, CompressLZ4HC, CompressZSTD, CompressNone:
+		return true, method, nil
+	}
+	compress, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, "", fmt.Errorf("unknown compression method: %s", s)
+	}
+	return compress, "", nil
+}
+
+func makeConnectTimeoutDialFunc(timeout, keepAlive time.Duration) DialFunc {
+	d := makeDefaultDialer(keepAlive)
 	d.Timeout = timeout
 	return d.DialContext
 }
@@ -0,0 +1,69 @@
+package chconn
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+	"github.com/vahid-sohrabloo/chconn/setting"
+)
+
+// deadlineExecutionTimeMargin is added to the remaining time of the deadline, so the client gives up before the server
+// and the query ends with the context error instead of the TIMEOUT_EXCEEDED exception
+const deadlineExecutionTimeMargin = time.Second
+
+// deadlineExecutionTime returns the max_execution_time in seconds for the remaining time of the deadline. It is
+// rounded up and at least one second, because zero is no limit.
+func deadlineExecutionTime(remaining time.Duration) uint64 {
+	remaining += deadlineExecutionTimeMargin
+	if remaining < time.Second {
+		return 1
+	}
+	return uint64((remaining + time.Second - 1) / time.Second)
+}
+
+// writeDeadlineSettings writes the max_execution_time and timeout_overflow_mode settings from the deadline of ctx.
+// It writes nothing if ctx has no deadline or the settings of the query have a smaller max_execution_time. The
+// timeout_overflow_mode of the query is kept.
+func writeDeadlineSettings(ctx context.Context, w *readerwriter.Writer, settings *setting.Settings, asString bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	maxExecutionTime := deadlineExecutionTime(time.Until(deadline))
+	if v := settingUint64(settings, "max_execution_time"); v != 0 && v <= maxExecutionTime {
+		return
+	}
+	w.String("max_execution_time")
+	if asString {
+		// flags
+		w.Uvarint(0)
+		w.String(strconv.FormatUint(maxExecutionTime, 10))
+	} else {
+		w.Uvarint(maxExecutionTime)
+	}
+	if settings != nil {
+		if _, ok := settings.Get("timeout_overflow_mode"); ok {
+			return
+		}
+	}
+	writeStringSetting(w, "timeout_overflow_mode", "throw", asString)
+}
+
+// settingUint64 returns the value of a numeric setting or zero if it is not set
+func settingUint64(settings *setting.Settings, name string) uint64 {
+	if settings == nil {
+		return 0
+	}
+	v, _ := settings.Get(name)
+	switch val := v.(type) {
+	case uint64:
+		return val
+	case int64:
+		if val > 0 {
+			return uint64(val)
+		}
+	}
+	return 0
+}
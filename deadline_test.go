@@ -0,0 +1,156 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+	"github.com/vahid-sohrabloo/chconn/setting"
+)
+
+func TestDeadlineExecutionTime(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, uint64(1), deadlineExecutionTime(-2*time.Second))
+	require.Equal(t, uint64(1), deadlineExecutionTime(0))
+	require.Equal(t, uint64(2), deadlineExecutionTime(100*time.Millisecond))
+	require.Equal(t, uint64(11), deadlineExecutionTime(10*time.Second))
+}
+
+func TestWriteDeadlineSettings(t *testing.T) {
+	t.Parallel()
+
+	expected := func(maxExecutionTime string) []byte {
+		w := readerwriter.NewWriter()
+		w.String("max_execution_time")
+		w.Uvarint(0)
+		w.String(maxExecutionTime)
+		w.String("timeout_overflow_mode")
+		w.Uvarint(0)
+		w.String("throw")
+		return w.Output().Bytes()
+	}
+
+	w := readerwriter.NewWriter()
+	writeDeadlineSettings(context.Background(), w, nil, true)
+	require.Empty(t, w.Output().Bytes())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	writeDeadlineSettings(ctx, w, nil, true)
+	require.Equal(t, expected("61"), w.Output().Bytes())
+
+	// a deadline under one second
+	ctxShort, cancelShort := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancelShort()
+	w.Reset()
+	writeDeadlineSettings(ctxShort, w, nil, true)
+	require.Equal(t, expected("2"), w.Output().Bytes())
+
+	// the expired deadline is never zero, because zero is no limit
+	ctxExpired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Minute))
+	defer cancelExpired()
+	w.Reset()
+	writeDeadlineSettings(ctxExpired, w, nil, true)
+	require.Equal(t, expected("1"), w.Output().Bytes())
+
+	// the smaller max_execution_time of the query is kept
+	settings := setting.NewSettings()
+	settings.MaxExecutionTime(5 * time.Second)
+	w.Reset()
+	writeDeadlineSettings(ctx, w, settings, true)
+	require.Empty(t, w.Output().Bytes())
+
+	// the larger max_execution_time of the query is overridden
+	settings.MaxExecutionTime(2 * time.Minute)
+	w.Reset()
+	writeDeadlineSettings(ctx, w, settings, true)
+	require.Equal(t, expected("61"), w.Output().Bytes())
+
+	// the timeout_overflow_mode of the query is kept
+	settings = setting.NewSettings()
+	settings.TimeoutOverflowMode("break")
+	w.Reset()
+	writeDeadlineSettings(ctx, w, settings, true)
+	onlyMaxExecutionTime := readerwriter.NewWriter()
+	onlyMaxExecutionTime.String("max_execution_time")
+	onlyMaxExecutionTime.Uvarint(0)
+	onlyMaxExecutionTime.String("61")
+	require.Equal(t, onlyMaxExecutionTime.Output().Bytes(), w.Output().Bytes())
+
+	// the servers before the settings are serialized as strings get the binary values without the flags
+	w.Reset()
+	writeDeadlineSettings(ctx, w, nil, false)
+	binary := readerwriter.NewWriter()
+	binary.String("max_execution_time")
+	binary.Uvarint(61)
+	binary.String("timeout_overflow_mode")
+	binary.String("throw")
+	require.Equal(t, binary.Output().Bytes(), w.Output().Bytes())
+}
+
+func TestDeadlineExecutionTimeQuery(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	config, err := ParseConfig(connString)
+	require.NoError(t, err)
+	config.DeadlineExecutionTime = true
+	conn, err := ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	getMaxExecutionTime := func(ctx context.Context, settings *setting.Settings) []uint64 {
+		stmt, err := conn.SelectWithSetting(ctx, `SELECT toUInt64(getSetting('max_execution_time'))`, settings)
+		require.NoError(t, err)
+		col := column.NewUint64(false)
+		var values []uint64
+		for stmt.Next() {
+			require.NoError(t, stmt.NextColumn(col))
+			col.ReadAll(&values)
+		}
+		require.NoError(t, stmt.Err())
+		stmt.Close()
+		return values
+	}
+
+	require.Equal(t, []uint64{0}, getMaxExecutionTime(context.Background(), nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.Equal(t, []uint64{61}, getMaxExecutionTime(ctx, nil))
+
+	// the smaller setting of the query is kept
+	settings := setting.NewSettings()
+	settings.MaxExecutionTime(5 * time.Second)
+	require.Equal(t, []uint64{5}, getMaxExecutionTime(ctx, settings))
+
+	// the larger setting of the query is overridden by the deadline
+	settings.MaxExecutionTime(2 * time.Minute)
+	require.Equal(t, []uint64{61}, getMaxExecutionTime(ctx, settings))
+
+	// a deadline under one second
+	ctxShort, cancelShort := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancelShort()
+	require.Equal(t, []uint64{2}, getMaxExecutionTime(ctxShort, nil))
+
+	// the timeout_overflow_mode of the query is kept
+	settings = setting.NewSettings()
+	settings.TimeoutOverflowMode("break")
+	stmt, err := conn.SelectWithSetting(ctx, `SELECT toString(getSetting('timeout_overflow_mode'))`, settings)
+	require.NoError(t, err)
+	colMode := column.NewString(false)
+	var modes []string
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(colMode))
+		colMode.ReadAllString(&modes)
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	require.Equal(t, []string{"break"}, modes)
+}
@@ -39,6 +39,33 @@ var ErrIPNotFound = errors.New("ip addr wasn't found")
 
 var ErrInsertMinColumn = errors.New("you should pass at least one column")
 
+// ErrParametersNotSupported when the query has parameters and the server doesn't support them
+var ErrParametersNotSupported = errors.New("query parameters are not supported by the server")
+
+// ErrCustomSettingsNotSupported when the query has custom settings and the server doesn't get the settings as strings
+var ErrCustomSettingsNotSupported = errors.New("custom settings are not supported by the server")
+
+// ErrInterserverSecretNotSupported when the server doesn't support the interserver secret
+var ErrInterserverSecretNotSupported = errors.New("interserver secret is not supported by the server")
+
+// ErrTablesStatusNotSupported when the server doesn't support the tables status request
+var ErrTablesStatusNotSupported = errors.New("tables status is not supported by the server")
+
+// ErrDrainLimit when SelectStmt.Close can not discard the rest of the result in the limits of CloseDrainTimeout and
+// CloseDrainMaxBytes of the config. The connection is closed
+var ErrDrainLimit = errors.New("drain limit exceeded")
+
+// ChecksumError is returned when the checksum of a compressed frame that is read from the server does not match its
+// data. The connection is closed because the rest of the data can not be trusted
+type ChecksumError = readerwriter.ChecksumError
+
+// needsClose reports if the connection must be closed after the error. The server ends the query when it sends an
+// exception, so the connection is still usable after a ChError
+func needsClose(err error) bool {
+	_, ok := err.(*ChError)
+	return !ok
+}
+
 // ChError represents an error reported by the Clickhouse server
 type ChError struct {
 	Code       int32
@@ -166,6 +193,21 @@ func (e *readError) Unwrap() error {
 	return e.err
 }
 
+// canceledError is the error of a canceled query that interrupted the connection. It wraps the context error and err
+// is the error of the interrupted read or write.
+type canceledError struct {
+	ctxErr error
+	err    error
+}
+
+func (e *canceledError) Error() string {
+	return fmt.Sprintf("%s (%s)", e.ctxErr.Error(), e.err.Error())
+}
+
+func (e *canceledError) Unwrap() error {
+	return e.ctxErr
+}
+
 type writeError struct {
 	msg string
 	err error
@@ -189,6 +231,10 @@ func redactPW(connString string) string {
 	connString = quotedDSN.ReplaceAllLiteralString(connString, "password=xxxxx")
 	plainDSN := regexp.MustCompile(`password=[^ ]*`)
 	connString = plainDSN.ReplaceAllLiteralString(connString, "password=xxxxx")
+	quotedSecretDSN := regexp.MustCompile(`cluster_secret='[^']*'`)
+	connString = quotedSecretDSN.ReplaceAllLiteralString(connString, "cluster_secret=xxxxx")
+	plainSecretDSN := regexp.MustCompile(`cluster_secret=[^ ]*`)
+	connString = plainSecretDSN.ReplaceAllLiteralString(connString, "cluster_secret=xxxxx")
 	return connString
 }
 
@@ -199,6 +245,10 @@ func redactURL(u *url.URL) string {
 	if _, pwSet := u.User.Password(); pwSet {
 		u.User = url.UserPassword(u.User.Username(), "xxxxx")
 	}
+	if query := u.Query(); query.Get("cluster_secret") != "" {
+		query.Set("cluster_secret", "xxxxx")
+		u.RawQuery = query.Encode()
+	}
 	return u.String()
 }
 
@@ -245,3 +295,19 @@ type NumberWriteError struct {
 func (e *NumberWriteError) Error() string {
 	return fmt.Sprintf("first column has %d rows but \"%s\"  column has %d rows", e.FirstNumRow, e.Column, e.NumRow)
 }
+
+type customSerializationError struct {
+	column string
+}
+
+func (e *customSerializationError) Error() string {
+	return fmt.Sprintf("custom serialization is not supported (column %s)", e.column)
+}
+
+type unsupportedTypeError struct {
+	chType string
+}
+
+func (e *unsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported column type: %s", e.chType)
+}
@@ -0,0 +1,25 @@
+package chconn
+
+import "time"
+
+// ExecResult is the result of a query that is executed with Exec.
+// The counters are the sum of the progress packets of the query.
+type ExecResult struct {
+	// QueryID is the id of the query. It is generated if the query options do not have it
+	QueryID      string
+	ReadRows     uint64
+	ReadBytes    uint64
+	WrittenRows  uint64
+	WrittenBytes uint64
+	// Elapsed is the time from sending the query until the server ends it
+	Elapsed time.Duration
+	// Profile is the profile info of the query or nil if the server does not send it
+	Profile *Profile
+}
+
+func (r *ExecResult) addProgress(p *Progress) {
+	r.ReadRows += p.ReadRows
+	r.ReadBytes += p.Readbytes
+	r.WrittenRows += p.WriterRows
+	r.WrittenBytes += p.WrittenBytes
+}
@@ -0,0 +1,62 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecResult(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(context.Background(), `DROP TABLE IF EXISTS clickhouse_test_exec_result`)
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), `CREATE TABLE clickhouse_test_exec_result (
+				id UInt64
+			) Engine=Memory`)
+	require.NoError(t, err)
+
+	var progressCount int
+	res, err := conn.ExecWithOption(context.Background(),
+		`INSERT INTO clickhouse_test_exec_result SELECT number FROM system.numbers LIMIT 100`,
+		&QueryOptions{
+			QueryID: "chconn_test_exec_result",
+			OnProgress: func(*Progress) {
+				progressCount++
+			},
+		})
+	require.NoError(t, err)
+	require.Equal(t, "chconn_test_exec_result", res.QueryID)
+	require.Equal(t, uint64(100), res.WrittenRows)
+	require.NotZero(t, res.WrittenBytes)
+	require.NotZero(t, res.Elapsed)
+	require.NotZero(t, progressCount)
+
+	// the query id is generated and the data of the select query is skipped
+	res, err = conn.Exec(context.Background(), `SELECT id FROM clickhouse_test_exec_result`)
+	require.NoError(t, err)
+	require.NotEmpty(t, res.QueryID)
+	require.Equal(t, uint64(100), res.ReadRows)
+	require.NotNil(t, res.Profile)
+	require.Equal(t, uint64(100), res.Profile.Rows)
+
+	// nothing is left unread on the connection
+	require.NoError(t, conn.Ping(context.Background()))
+}
+
+func TestExecResultAddProgress(t *testing.T) {
+	t.Parallel()
+
+	res := &ExecResult{}
+	res.addProgress(&Progress{ReadRows: 1, Readbytes: 2, WriterRows: 3, WrittenBytes: 4})
+	res.addProgress(&Progress{ReadRows: 10, Readbytes: 20, WriterRows: 30, WrittenBytes: 40})
+	require.Equal(t, &ExecResult{ReadRows: 11, ReadBytes: 22, WrittenRows: 33, WrittenBytes: 44}, res)
+}
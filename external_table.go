@@ -0,0 +1,40 @@
+package chconn
+
+import (
+	"github.com/vahid-sohrabloo/chconn/column"
+)
+
+// ExternalTable is client data that is sent with the query and can be used in the query like a temporary table.
+// e.g. SELECT * FROM t WHERE id IN _ext
+type ExternalTable struct {
+	Name    string
+	block   *Block
+	columns []column.Column
+}
+
+// NewExternalTable create an external table with the name that is used in the query.
+func NewExternalTable(name string) *ExternalTable {
+	return &ExternalTable{
+		Name:  name,
+		block: newBlock(),
+	}
+}
+
+// AddColumn add a column with the ClickHouse type (e.g. "UInt64" or "Nullable(String)") and its data
+func (t *ExternalTable) AddColumn(name, chType string, data column.Column) *ExternalTable {
+	t.block.Columns = append(t.block.Columns, &Column{
+		Name:   name,
+		ChType: chType,
+	})
+	t.block.NumColumns++
+	t.columns = append(t.columns, data)
+	return t
+}
+
+func (t *ExternalTable) write(ch *conn) error {
+	err := ch.sendData(t.block, t.Name, t.columns[0].NumRow())
+	if err != nil {
+		return err
+	}
+	return t.block.writeColumsBuffer(ch, t.columns...)
+}
@@ -0,0 +1,55 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+)
+
+func TestExternalTable(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	colID := column.NewUint64(false)
+	colName := column.NewString(false)
+	for i := 0; i < 5; i++ {
+		colID.Append(uint64(i * 2))
+		colName.AppendString("name")
+	}
+	ext := NewExternalTable("_ext").
+		AddColumn("id", "UInt64", colID).
+		AddColumn("name", "String", colName)
+
+	stmt, err := conn.SelectWithOption(context.Background(),
+		`SELECT number FROM system.numbers WHERE number IN (SELECT id FROM _ext) LIMIT 3`,
+		&QueryOptions{
+			ExternalTables: []*ExternalTable{ext},
+		})
+	require.NoError(t, err)
+	var nums []uint64
+	col := column.NewUint64(false)
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(col))
+		col.ReadAll(&nums)
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	assert.Equal(t, []uint64{0, 2, 4}, nums)
+
+	res, err := conn.ExecWithOption(context.Background(),
+		`SELECT count() FROM _ext WHERE name = 'invalid'`,
+		&QueryOptions{
+			ExternalTables: []*ExternalTable{NewExternalTable("_ext")},
+		})
+	require.Equal(t, ErrInsertMinColumn, err)
+	require.Nil(t, res)
+}
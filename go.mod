@@ -6,6 +6,7 @@ require (
 	github.com/dave/jennifer v1.5.0 // indirect
 	github.com/google/uuid v1.3.0
 	github.com/jackc/puddle v1.2.1
+	github.com/klauspost/compress v1.13.6
 	github.com/pierrec/lz4/v4 v4.1.12
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/stretchr/testify v1.7.0
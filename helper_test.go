@@ -2,6 +2,12 @@ package chconn
 
 import (
 	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
 )
 
 type readErrorHelper struct {
@@ -33,3 +39,34 @@ func (w *writerErrorHelper) Write(p []byte) (int, error) {
 	}
 	return w.w.Write(p)
 }
+
+// startSilentServer starts a server that answers the hello of the client and then never answers anything else.
+// It returns the connection string of the server.
+func startSilentServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		w := readerwriter.NewWriter()
+		w.Uvarint(serverHello)
+		w.String("ClickHouse")
+		w.Uvarint(21)
+		w.Uvarint(8)
+		// a revision without the timezone, the display name and the addendum
+		w.Uvarint(54000)
+		if _, err := w.WriteTo(c); err != nil {
+			return
+		}
+		io.Copy(ioutil.Discard, c) //nolint:errcheck //no need
+	}()
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	return "host=" + host + " port=" + port
+}
@@ -8,24 +8,88 @@ import (
 )
 
 type InsertStmt interface {
+	// Write sends the columns as a block of the insert and resets the columns for the next block.
+	// It can be called many times before Commit to insert more data than fits in memory.
+	// NOTE: Commit or Close must be called after Write even if Write returns an error
+	Write(ctx context.Context, columns ...column.Column) error
+	// Commit sends the columns as the last block and ends the insert. The columns can be omitted if the data is already
+	// sent with Write. The columns are not reset, so they can be sent again if Commit returns an error
 	Commit(ctx context.Context, columns ...column.Column) error
+	// Close ends the insert without sending more data and unlocks the connection. It does nothing after Commit, so it
+	// can be deferred.
+	// NOTE: the server does not roll back the blocks that are already sent with Write
+	Close(ctx context.Context) error
 	GetBlock() *Block
+	// TableColumns get the description of the table columns (e.g. the default values).
+	// It is nil if the server does not send it (input_format_defaults_for_omitted_fields setting is disabled)
+	TableColumns() []TableColumn
 }
 type insertStmt struct {
-	block      *Block
-	conn       *conn
-	query      string
-	queryID    string
-	stage      queryProcessingStage
-	settings   *setting.Settings
-	clientInfo *ClientInfo
+	block        *Block
+	conn         *conn
+	query        string
+	queryID      string
+	stage        QueryProcessingStage
+	settings     *setting.Settings
+	clientInfo   *ClientInfo
+	onProgress   func(*Progress)
+	onProfile    func(*Profile)
+	tableColumns []TableColumn
+	// written is true if a block is sent with Write
+	written bool
+	// err is the error of Write that failed the insert
+	err error
+	// done is true when the insert is ended by Commit or Close
+	done bool
 }
 
-func (s *insertStmt) commit(columns ...column.Column) error {
-	if len(columns) == 0 {
+func (s *insertStmt) commit(ctx context.Context, columns ...column.Column) error {
+	if len(columns) == 0 && !s.written {
 		return ErrInsertMinColumn
 	}
-	err := s.conn.sendData(s.block, columns[0].NumRow())
+	if len(columns) != 0 {
+		if err := s.writeBlock(ctx, columns...); err != nil {
+			return err
+		}
+	}
+	if err := s.writeEnd(ctx); err != nil {
+		return err
+	}
+	return s.readEnd()
+}
+
+// readEnd reads the response of the server until the end of the insert.
+func (s *insertStmt) readEnd() error {
+	for {
+		res, err := s.conn.reciveAndProccessData(s.onProgress)
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			return nil
+		}
+		if profile, ok := res.(*Profile); ok {
+			if s.onProfile != nil {
+				s.onProfile(profile)
+			}
+			continue
+		}
+		return &unexpectedPacket{expected: "serverEndOfStream", actual: res}
+	}
+}
+
+// writeBlock writes the block of columns.
+func (s *insertStmt) writeBlock(ctx context.Context, columns ...column.Column) error {
+	// an empty block is the end of the data
+	if columns[0].NumRow() == 0 {
+		return nil
+	}
+	s.conn.writeMu.Lock()
+	defer s.conn.writeMu.Unlock()
+	if s.conn.canceled {
+		return ctx.Err()
+	}
+	err := s.conn.sendData(s.block, "", columns[0].NumRow())
 	if err != nil {
 		return &InsertError{
 			err:   err,
@@ -33,36 +97,92 @@ func (s *insertStmt) commit(columns ...column.Column) error {
 		}
 	}
 
-	err = s.block.writeColumsBuffer(s.conn, columns...)
-	if err != nil {
-		return err
-	}
-
-	err = s.conn.sendData(newBlock(), 0)
+	return s.block.writeColumsBuffer(s.conn, columns...)
+}
 
-	if err != nil {
-		return err
+// writeEnd writes the empty block that ends the data.
+func (s *insertStmt) writeEnd(ctx context.Context) error {
+	s.conn.writeMu.Lock()
+	defer s.conn.writeMu.Unlock()
+	if s.conn.canceled {
+		return ctx.Err()
 	}
+	return s.conn.sendData(newBlock(), "", 0)
+}
 
-	res, err := s.conn.reciveAndProccessData(emptyOnProgress)
+// Write sends the columns as a block of the insert and resets the columns for the next block.
+// It can be called many times before Commit to insert more data than fits in memory.
+// NOTE: Commit or Close must be called after Write even if Write returns an error
+func (s *insertStmt) Write(ctx context.Context, columns ...column.Column) error {
+	if s.err != nil {
+		return s.err
+	}
+	if len(columns) == 0 {
+		return ErrInsertMinColumn
+	}
+	s.conn.watch(ctx)
+	defer s.conn.contextWatcher.Unwatch()
+	err := s.writeBlock(ctx, columns...)
+	if s.conn.isCanceled() {
+		s.err = s.conn.finishCanceled(ctx, nil, err)
+		return s.err
+	}
 	if err != nil {
+		s.err = err
+		s.conn.Close(context.Background())
 		return err
 	}
-
-	if res != nil {
-		return &unexpectedPacket{expected: "serverEndOfStream", actual: res}
+	for _, col := range columns {
+		col.Reset()
 	}
-
+	s.written = true
 	return nil
 }
 
-// Commit all columns to ClickHoouse
+// Close ends the insert without sending more data and unlocks the connection. It does nothing after Commit, so it can
+// be deferred.
+// NOTE: the server does not roll back the blocks that are already sent with Write
+func (s *insertStmt) Close(ctx context.Context) error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+	if s.err != nil {
+		s.conn.unlock()
+		return s.err
+	}
+	s.conn.watch(ctx)
+	defer s.conn.contextWatcher.Unwatch()
+	defer s.conn.unlock()
+	err := s.writeEnd(ctx)
+	if err == nil {
+		err = s.readEnd()
+	}
+	if s.conn.isCanceled() {
+		return s.conn.finishCanceled(ctx, nil, err)
+	}
+	if err != nil && needsClose(err) {
+		s.conn.Close(context.Background())
+	}
+	return err
+}
+
+// Commit sends the columns as the last block and ends the insert. The columns can be omitted if the data is already
+// sent with Write
 func (s *insertStmt) Commit(ctx context.Context, columns ...column.Column) error {
-	s.conn.contextWatcher.Watch(ctx)
+	s.done = true
+	if s.err != nil {
+		s.conn.unlock()
+		return s.err
+	}
+	s.conn.watch(ctx)
 	defer s.conn.contextWatcher.Unwatch()
 	defer s.conn.unlock()
-	err := s.commit(columns...)
-	if err != nil {
+	err := s.commit(ctx, columns...)
+	if s.conn.isCanceled() {
+		return s.conn.finishCanceled(ctx, nil, err)
+	}
+	if err != nil && needsClose(err) {
 		s.conn.Close(context.Background())
 	}
 	return err
@@ -72,3 +192,9 @@ func (s *insertStmt) Commit(ctx context.Context, columns ...column.Column) error
 func (s *insertStmt) GetBlock() *Block {
 	return s.block
 }
+
+// TableColumns get the description of the table columns (e.g. the default values).
+// It is nil if the server does not send it (input_format_defaults_for_omitted_fields setting is disabled)
+func (s *insertStmt) TableColumns() []TableColumn {
+	return s.tableColumns
+}
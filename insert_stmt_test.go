@@ -1,15 +1,21 @@
 package chconn
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/internal/ctxwatch"
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
 	"github.com/vahid-sohrabloo/chconn/setting"
 )
 
@@ -102,14 +108,14 @@ func TestInsert(t *testing.T) {
 
 	res, err := conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_insert`)
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	res, err = conn.Exec(context.Background(), `CREATE TABLE test_insert (
 				int8 Int8
 			) Engine=Memory`)
 
 	require.NoError(t, err)
-	require.Nil(t, res)
+	require.NotNil(t, res)
 
 	col := column.NewInt8(false)
 
@@ -155,3 +161,196 @@ func TestInsert(t *testing.T) {
 
 	conn.RawConn().Close()
 }
+
+func TestInsertWrite(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_insert_write`)
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), `CREATE TABLE test_insert_write (
+				id UInt64,
+				tags Array(LowCardinality(String))
+			) Engine=Memory`)
+	require.NoError(t, err)
+
+	insertStmt, err := conn.Insert(context.Background(), `INSERT INTO test_insert_write (id, tags) VALUES`)
+	require.NoError(t, err)
+
+	col := column.NewUint64(false)
+	colTagsValues := column.NewString(false)
+	colTags := column.NewArray(column.NewLC(colTagsValues))
+	var colInsert []uint64
+	for block := 0; block < 3; block++ {
+		for i := 0; i < 10; i++ {
+			id := uint64(block*10 + i)
+			col.Append(id)
+			colInsert = append(colInsert, id)
+			colTags.AppendLen(1)
+			colTagsValues.AppendDict([]byte(fmt.Sprintf("block_%d", block)))
+		}
+		require.NoError(t, insertStmt.Write(context.Background(), col, colTags))
+		assert.Equal(t, 0, col.NumRow())
+	}
+	require.NoError(t, insertStmt.Commit(context.Background()))
+
+	selectStmt, err := conn.Select(context.Background(), `SELECT id, tags[1] FROM test_insert_write ORDER BY id`)
+	require.NoError(t, err)
+	colTag := column.NewString(false)
+	var colData []uint64
+	var tags []string
+	for selectStmt.Next() {
+		require.NoError(t, selectStmt.NextColumn(col))
+		require.NoError(t, selectStmt.NextColumn(colTag))
+		col.ReadAll(&colData)
+		colTag.ReadAllString(&tags)
+	}
+	require.NoError(t, selectStmt.Err())
+	selectStmt.Close()
+
+	assert.Equal(t, colInsert, colData)
+	require.Len(t, tags, 30)
+	assert.Equal(t, "block_0", tags[0])
+	assert.Equal(t, "block_2", tags[29])
+
+	// an insert that is closed after some blocks keeps the blocks and the connection
+	insertStmt, err = conn.Insert(context.Background(), `INSERT INTO test_insert_write (id, tags) VALUES`)
+	require.NoError(t, err)
+	col.Append(100)
+	colTags.AppendLen(1)
+	colTagsValues.AppendDict([]byte("closed"))
+	require.NoError(t, insertStmt.Write(context.Background(), col, colTags))
+	require.NoError(t, insertStmt.Close(context.Background()))
+	require.False(t, conn.IsClosed())
+
+	selectStmt, err = conn.Select(context.Background(), `SELECT id FROM test_insert_write WHERE id = 100`)
+	require.NoError(t, err)
+	colData = colData[:0]
+	for selectStmt.Next() {
+		require.NoError(t, selectStmt.NextColumn(col))
+		col.ReadAll(&colData)
+	}
+	require.NoError(t, selectStmt.Err())
+	selectStmt.Close()
+	assert.Equal(t, []uint64{100}, colData)
+}
+
+func TestInsertClose(t *testing.T) {
+	t.Parallel()
+
+	r := readerwriter.NewWriter()
+	r.Uvarint(serverEndOfStream)
+	client, server := net.Pipe()
+	defer server.Close()
+	var sent bytes.Buffer
+	c := &conn{
+		conn:           client,
+		config:         &Config{},
+		status:         connStatusBusy,
+		writer:         readerwriter.NewWriter(),
+		writerto:       &sent,
+		reader:         readerwriter.NewReader(r.Output()),
+		contextWatcher: ctxwatch.NewContextWatcher(func() {}, func() {}),
+	}
+	c.writertoCompress = c.writerto
+	block := newBlock()
+	block.NumColumns = 1
+	block.Columns = []*Column{{Name: "int8", ChType: "Int8"}}
+	stmt := &insertStmt{block: block, conn: c}
+
+	col := column.NewInt8(false)
+	col.Append(1)
+	col.Append(2)
+	require.NoError(t, stmt.Write(context.Background(), col))
+	written := sent.Len()
+
+	// the insert is ended after the partial write and the connection can be used again
+	require.NoError(t, stmt.Close(context.Background()))
+	require.Greater(t, sent.Len(), written)
+	require.False(t, c.IsClosed())
+	require.False(t, c.IsBusy())
+
+	// Close does nothing after the insert is ended
+	written = sent.Len()
+	require.NoError(t, stmt.Close(context.Background()))
+	require.Equal(t, written, sent.Len())
+}
+
+func TestInsertCommitErrorKeepsColumns(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer server.Close()
+	c := &conn{
+		conn:           client,
+		config:         &Config{},
+		status:         connStatusBusy,
+		writer:         readerwriter.NewWriter(),
+		contextWatcher: ctxwatch.NewContextWatcher(func() {}, func() {}),
+	}
+	// the block header and the column header are written and the data of the column fails
+	c.writerto = &writerErrorHelper{
+		err:         errors.New("timeout"),
+		w:           ioutil.Discard,
+		numberValid: 2,
+	}
+	c.writertoCompress = c.writerto
+	block := newBlock()
+	block.NumColumns = 1
+	block.Columns = []*Column{{Name: "int8", ChType: "Int8"}}
+	stmt := &insertStmt{block: block, conn: c}
+
+	col := column.NewInt8(false)
+	for i := 1; i <= 10; i++ {
+		col.Append(int8(i))
+	}
+	err := stmt.Commit(context.Background(), col)
+	require.EqualError(t, errors.Unwrap(err), "timeout")
+	require.True(t, c.IsClosed())
+	// the columns are not reset, so the same data can be sent again (e.g. chpool InsertWithRetry)
+	require.Equal(t, 10, col.NumRow())
+}
+
+func TestAsyncInsert(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(context.Background(), `DROP TABLE IF EXISTS test_async_insert`)
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), `CREATE TABLE test_async_insert (
+				id UInt64
+			) Engine=MergeTree ORDER BY id`)
+	require.NoError(t, err)
+
+	settings := setting.NewSettings()
+	settings.AsyncInsert(true)
+	settings.WaitForAsyncInsert(true)
+	insertStmt, err := conn.InsertWithSetting(context.Background(), `INSERT INTO test_async_insert VALUES`, settings, "")
+	require.NoError(t, err)
+
+	col := column.NewUint64(false)
+	col.Append(1)
+	col.Append(2)
+	require.NoError(t, insertStmt.Commit(context.Background(), col))
+
+	selectStmt, err := conn.Select(context.Background(), `SELECT id FROM test_async_insert ORDER BY id`)
+	require.NoError(t, err)
+	var ids []uint64
+	for selectStmt.Next() {
+		require.NoError(t, selectStmt.NextColumn(col))
+		col.ReadAll(&ids)
+	}
+	require.NoError(t, selectStmt.Err())
+	selectStmt.Close()
+	assert.Equal(t, []uint64{1, 2}, ids)
+}
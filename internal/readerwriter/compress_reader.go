@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
+	"github.com/vahid-sohrabloo/chconn/internal/cityhash102"
 )
 
 var ErrHeaderDecompressEOF = errors.New("lz4 decompression header EOF")
@@ -20,6 +22,29 @@ func (e *invalidCompressErr) Error() string {
 	return fmt.Sprintf("unknown compression method: 0x%02x ", e.method)
 }
 
+// ChecksumError is returned when the checksum of a compressed frame does not match its data (e.g. the data is
+// corrupted on the network)
+type ChecksumError struct {
+	// Frame is the number of the frame in the connection. It starts from 1
+	Frame            uint64
+	Method           byte
+	CompressedSize   int
+	DecompressedSize int
+	// Expected is the checksum in the frame header and Actual is the checksum of the received data.
+	// The lower 64 bits are the first element
+	Expected [2]uint64
+	Actual   [2]uint64
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf(
+		"checksum mismatch in compressed frame %d (method: 0x%02x, compressed size: %d, decompressed size: %d): "+
+			"expected %016x%016x got %016x%016x",
+		e.Frame, e.Method, e.CompressedSize, e.DecompressedSize,
+		e.Expected[1], e.Expected[0], e.Actual[1], e.Actual[0],
+	)
+}
+
 type compressReader struct {
 	reader io.Reader
 	// data uncompressed
@@ -30,6 +55,12 @@ type compressReader struct {
 	zdata []byte
 	// lz4 headers
 	header []byte
+	// number of the frames that are read
+	frame uint64
+	// skipChecksum disables the verification of the checksums
+	skipChecksum bool
+	// zstd decoder. it is created for the first zstd block
+	zstdDecoder *zstd.Decoder
 }
 
 // NewCompressReader wrap the io.Reader
@@ -81,8 +112,12 @@ func (cr *compressReader) readCompressedData() (err error) {
 		return ErrHeaderDecompressEOF
 	}
 
-	compressedSize := int(binary.LittleEndian.Uint32(cr.header[17:])) - 9
+	// the compressed size includes the method and the sizes
+	compressedSize := int(binary.LittleEndian.Uint32(cr.header[17:]))
 	decompressedSize := int(binary.LittleEndian.Uint32(cr.header[21:]))
+	if compressedSize < CompressHeaderSize {
+		return ErrDecompressSizeError
+	}
 	if compressedSize > cap(cr.zdata) {
 		cr.zdata = make([]byte, compressedSize)
 	}
@@ -90,27 +125,74 @@ func (cr *compressReader) readCompressedData() (err error) {
 		cr.data = make([]byte, decompressedSize)
 	}
 
+	// zdata keeps the method and the sizes because the checksum is calculated with them
 	cr.zdata = cr.zdata[:compressedSize]
 	cr.data = cr.data[:decompressedSize]
+	copy(cr.zdata, cr.header[ChecksumSize:])
 
-	// @TODO checksum
-	if cr.header[16] == LZ4 {
-		n, err = io.ReadFull(cr.reader, cr.zdata)
-		if err != nil {
-			return
+	n, err = io.ReadFull(cr.reader, cr.zdata[CompressHeaderSize:])
+	if err != nil {
+		return
+	}
+	if n != len(cr.zdata)-CompressHeaderSize {
+		return ErrDecompressSizeError
+	}
+	cr.frame++
+
+	if !cr.skipChecksum {
+		if err := cr.checkChecksum(); err != nil {
+			return err
 		}
+	}
 
-		if n != len(cr.zdata) {
+	zdata := cr.zdata[CompressHeaderSize:]
+	switch cr.header[16] {
+	case NONE:
+		if len(zdata) != decompressedSize {
 			return ErrDecompressSizeError
 		}
-
-		_, err = lz4.UncompressBlock(cr.zdata, cr.data)
+		copy(cr.data, zdata)
+		return nil
+	case LZ4:
+		_, err = lz4.UncompressBlock(zdata, cr.data)
+		return err
+	case ZSTD:
+		if cr.zstdDecoder == nil {
+			cr.zstdDecoder, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return err
+			}
+		}
+		var data []byte
+		data, err = cr.zstdDecoder.DecodeAll(zdata, cr.data[:0])
 		if err != nil {
-			return
+			return err
+		}
+		if len(data) != decompressedSize {
+			return ErrDecompressSizeError
 		}
-	} else {
-		return &invalidCompressErr{cr.header[16]}
+		cr.data = data
+		return nil
 	}
+	return &invalidCompressErr{cr.header[16]}
+}
 
-	return nil
+// checkChecksum compares the checksum of the frame header with the cityhash128 of the compressed data.
+func (cr *compressReader) checkChecksum() error {
+	checkSum := cityhash102.CityHash128(cr.zdata, uint32(len(cr.zdata)))
+	expected := [2]uint64{
+		binary.LittleEndian.Uint64(cr.header[0:]),
+		binary.LittleEndian.Uint64(cr.header[8:]),
+	}
+	if expected[0] == checkSum.Lower64() && expected[1] == checkSum.Higher64() {
+		return nil
+	}
+	return &ChecksumError{
+		Frame:            cr.frame,
+		Method:           cr.header[16],
+		CompressedSize:   len(cr.zdata),
+		DecompressedSize: len(cr.data),
+		Expected:         expected,
+		Actual:           [2]uint64{checkSum.Lower64(), checkSum.Higher64()},
+	}
 }
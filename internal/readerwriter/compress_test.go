@@ -0,0 +1,116 @@
+package readerwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, BlockMaxSize+100)
+	for i := range data {
+		data[i] = byte(i % 100)
+	}
+
+	tests := []struct {
+		name      string
+		method    byte
+		level     int
+		blockSize int
+	}{
+		{name: "none", method: NONE},
+		{name: "lz4", method: LZ4},
+		{name: "lz4hc", method: LZ4, level: 9},
+		{name: "zstd", method: ZSTD},
+		{name: "zstd level", method: ZSTD, level: 10},
+		{name: "lz4 block size", method: LZ4, blockSize: 1024},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w := NewCompressWriter(&buf, tt.method, tt.level, tt.blockSize)
+			_, err := w.Write(data)
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+			if tt.method == NONE {
+				require.Greater(t, buf.Len(), len(data))
+			} else {
+				require.Less(t, buf.Len(), len(data))
+			}
+			require.Equal(t, tt.method, buf.Bytes()[16])
+			if tt.blockSize > 0 {
+				require.Equal(t, uint32(tt.blockSize), binary.LittleEndian.Uint32(buf.Bytes()[21:]))
+			}
+
+			r := NewCompressReader(&buf)
+			readData := make([]byte, len(data))
+			_, err = io.ReadFull(r, readData)
+			require.NoError(t, err)
+			require.Equal(t, data, readData)
+		})
+	}
+}
+
+func TestCompressInvalidMethod(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := NewCompressWriter(&buf, LZ4, 0, 0)
+	_, err := w.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	buf.Bytes()[16] = 0x01
+
+	r := NewCompressReader(&buf)
+	r.skipChecksum = true
+	_, err = r.Read(make([]byte, 4))
+	require.EqualError(t, err, "unknown compression method: 0x01 ")
+}
+
+func TestCompressChecksum(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := NewCompressWriter(&buf, LZ4, 0, 0)
+	for i := 0; i < 2; i++ {
+		_, err := w.Write([]byte("data"))
+		require.NoError(t, err)
+		require.NoError(t, w.Flush())
+	}
+	data := buf.Bytes()
+	frameSize := len(data) / 2
+	// corrupt the checksum of the second frame
+	data[frameSize] ^= 0xff
+
+	r := NewCompressReader(bytes.NewReader(data))
+	_, err := io.ReadFull(r, make([]byte, 4))
+	require.NoError(t, err)
+	_, err = io.ReadFull(r, make([]byte, 4))
+	var checksumErr *ChecksumError
+	require.True(t, errors.As(err, &checksumErr))
+	require.Equal(t, uint64(2), checksumErr.Frame)
+	require.Equal(t, byte(LZ4), checksumErr.Method)
+	require.Equal(t, frameSize-ChecksumSize, checksumErr.CompressedSize)
+	require.Equal(t, 4, checksumErr.DecompressedSize)
+	require.NotEqual(t, checksumErr.Expected, checksumErr.Actual)
+	require.Contains(t, err.Error(), "checksum mismatch in compressed frame 2")
+
+	// the checksum is not verified if it is disabled
+	reader := NewReader(bytes.NewReader(data))
+	reader.SetCompress(true)
+	reader.SetChecksum(false)
+	_, err = reader.FixedString(4)
+	require.NoError(t, err)
+	readData, err := reader.FixedString(4)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), readData)
+}
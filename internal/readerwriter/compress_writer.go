@@ -4,27 +4,57 @@ import (
 	"encoding/binary"
 	"io"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 	"github.com/vahid-sohrabloo/chconn/internal/cityhash102"
 )
 
 type compressWriter struct {
 	writer io.Writer
+	// compression method (NONE, LZ4 or ZSTD)
+	method byte
+	// lz4 compressor of the high compression mode. it is nil for the fast mode
+	lz4hc *lz4.CompressorHC
 	// data uncompressed
 	data []byte
 	// data position
 	pos int
 	// data compressed
 	zdata []byte
+	// zstd encoder. it is only created for ZSTD
+	zstdEncoder *zstd.Encoder
 }
 
-// NewCompressWriter wrap the io.Writer
-func NewCompressWriter(w io.Writer) *compressWriter {
-	p := &compressWriter{writer: w}
-	p.data = make([]byte, BlockMaxSize)
+// NewCompressWriter wrap the io.Writer with the compression method (NONE, LZ4 or ZSTD).
+// level is the compression level and zero is the default level of the method. a level for LZ4 enables the high
+// compression mode (LZ4HC) with the level 1-9. blockSize is the max size of the uncompressed data in each frame and
+// zero is BlockMaxSize
+func NewCompressWriter(w io.Writer, method byte, level, blockSize int) *compressWriter {
+	if blockSize <= 0 {
+		blockSize = BlockMaxSize
+	}
+	p := &compressWriter{
+		writer: w,
+		method: method,
+	}
+	p.data = make([]byte, blockSize)
 
-	zlen := lz4.CompressBlockBound(BlockMaxSize) + HeaderSize
+	zlen := lz4.CompressBlockBound(blockSize) + HeaderSize
 	p.zdata = make([]byte, zlen)
+	switch {
+	case method == ZSTD:
+		zstdLevel := zstd.SpeedDefault
+		if level > 0 {
+			zstdLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		// the options are valid, so it never returns an error
+		p.zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstdLevel))
+	case method == LZ4 && level > 0:
+		if level > 9 {
+			level = 9
+		}
+		p.lz4hc = &lz4.CompressorHC{Level: lz4.Level1 << (level - 1)}
+	}
 	return p
 }
 
@@ -53,14 +83,28 @@ func (cw *compressWriter) Flush() (err error) {
 		return
 	}
 
-	compressedSize, err := lz4.CompressBlock(cw.data[:cw.pos], cw.zdata[HeaderSize:], nil)
-	if err != nil {
-		return err
+	var compressedSize int
+	switch {
+	case cw.method == NONE:
+		compressedSize = copy(cw.zdata[HeaderSize:], cw.data[:cw.pos])
+	case cw.method == ZSTD:
+		cw.zdata = cw.zstdEncoder.EncodeAll(cw.data[:cw.pos], cw.zdata[:HeaderSize])
+		compressedSize = len(cw.zdata) - HeaderSize
+	case cw.lz4hc != nil:
+		compressedSize, err = cw.lz4hc.CompressBlock(cw.data[:cw.pos], cw.zdata[HeaderSize:])
+		if err != nil {
+			return err
+		}
+	default:
+		compressedSize, err = lz4.CompressBlock(cw.data[:cw.pos], cw.zdata[HeaderSize:], nil)
+		if err != nil {
+			return err
+		}
 	}
 
 	compressedSize += CompressHeaderSize
 	// fill the header, compressed_size_32 + uncompressed_size_32
-	cw.zdata[16] = LZ4
+	cw.zdata[16] = cw.method
 
 	binary.LittleEndian.PutUint32(cw.zdata[17:], uint32(compressedSize))
 	binary.LittleEndian.PutUint32(cw.zdata[21:], uint32(cw.pos))
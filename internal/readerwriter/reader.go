@@ -9,7 +9,7 @@ import (
 type Reader struct {
 	mainReader     io.Reader
 	input          io.Reader
-	compressReader io.Reader
+	compressReader *compressReader
 	scratch        [binary.MaxVarintLen64]byte
 }
 
@@ -30,6 +30,11 @@ func (r *Reader) SetCompress(c bool) {
 	r.input = r.mainReader
 }
 
+// SetChecksum enables or disables the verification of the checksums of the compressed data. It is enabled by default
+func (r *Reader) SetChecksum(c bool) {
+	r.compressReader.skipChecksum = !c
+}
+
 // Bool read bool value
 func (r *Reader) Bool() (bool, error) {
 	v, err := r.ReadByte()
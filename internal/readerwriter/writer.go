@@ -27,6 +27,12 @@ func (w *Writer) Uvarint(v uint64) {
 	w.Write(w.scratch[:ln])
 }
 
+// Varint write a variable int64 value (zigzag encoded) into writer
+func (w *Writer) Varint(v int64) {
+	ln := binary.PutVarint(w.scratch[:binary.MaxVarintLen64], v)
+	w.Write(w.scratch[:ln])
+}
+
 // Bool write bool value
 func (w *Writer) Bool(v bool) {
 	if v {
@@ -0,0 +1,49 @@
+package chconn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"strconv"
+)
+
+// interserverUserMarker is the user of the hello packet that connects with the interserver secret of the cluster
+const interserverUserMarker = " INTERSERVER SECRET "
+
+// interserverSaltSize is the max size of the salt that the server reads
+const interserverSaltSize = 32
+
+// newInterserverSalt generates the random salt of the connection that is used in the hash of the queries.
+func newInterserverSalt() (string, error) {
+	salt := make([]byte, interserverSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return string(salt), nil
+}
+
+// interserverHash is the hash of the query that proves the connection knows the secret of the cluster.
+// The server calculates the same hash and only runs the query on behalf of the initial user if they match.
+func interserverHash(salt string, nonce *uint64, secret, query, queryID, initialUser string) string {
+	data := salt
+	if nonce != nil {
+		data += strconv.FormatUint(*nonce, 10)
+	}
+	data += secret + query + queryID + initialUser
+	hash := sha256.Sum256([]byte(data))
+	return string(hash[:])
+}
+
+// writeInterserverHash writes the hash of the query or an empty string if the connection does not use the
+// interserver secret.
+func (ch *conn) writeInterserverHash(query, queryID, initialUser string) {
+	if ch.config.ClusterSecret == "" {
+		ch.writer.String("")
+		return
+	}
+	var nonce *uint64
+	// the older servers do not send the nonce
+	if ch.serverInfo.Revision >= dbmsMinRevisionWithInterserverSecretV2 {
+		nonce = &ch.serverInfo.nonce
+	}
+	ch.writer.String(interserverHash(ch.interserverSalt, nonce, ch.config.ClusterSecret, query, queryID, initialUser))
+}
@@ -0,0 +1,75 @@
+package chconn
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+)
+
+func TestInterserverHash(t *testing.T) {
+	t.Parallel()
+
+	nonce := uint64(42)
+	hash := interserverHash("salt", &nonce, "secret", "SELECT 1", "query_id", "user")
+	require.Equal(t, "2c5aa178a4c831670e29176d834c6591cdae30fe8fa3b50fd0f7d4e8636e3c54", hex.EncodeToString([]byte(hash)))
+
+	// the older servers do not send the nonce
+	hash = interserverHash("salt", nil, "secret", "SELECT 1", "query_id", "user")
+	require.Equal(t, "ad16fb7177bfba25f7023b9141a722a4c223faa205d67b34a28df66c744345aa", hex.EncodeToString([]byte(hash)))
+
+	salt, err := newInterserverSalt()
+	require.NoError(t, err)
+	require.Len(t, salt, interserverSaltSize)
+}
+
+func TestRedactClusterSecret(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t,
+		"host=localhost cluster=test cluster_secret=xxxxx",
+		redactPW("host=localhost cluster=test cluster_secret=123"),
+	)
+	require.Equal(t,
+		"clickhouse://localhost:9000/db?cluster=test&cluster_secret=xxxxx",
+		redactPW("clickhouse://localhost:9000/db?cluster=test&cluster_secret=123"),
+	)
+}
+
+func TestInterserverSecret(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+	cluster := os.Getenv("CHX_TEST_CLUSTER")
+	secret := os.Getenv("CHX_TEST_CLUSTER_SECRET")
+	if cluster == "" || secret == "" {
+		t.Skip("CHX_TEST_CLUSTER and CHX_TEST_CLUSTER_SECRET are not set")
+	}
+
+	config, err := ParseConfig(connString)
+	require.NoError(t, err)
+	config.Cluster = cluster
+	config.ClusterSecret = secret
+
+	conn, err := ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	stmt, err := conn.SelectWithOption(context.Background(), "SELECT currentUser()", &QueryOptions{
+		InitialUser:    "default",
+		InitialQueryID: "chconn_test_interserver_secret",
+	})
+	require.NoError(t, err)
+	col := column.NewString(false)
+	var users []string
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(col))
+		col.ReadAllString(&users)
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	require.Equal(t, []string{"default"}, users)
+}
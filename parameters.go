@@ -0,0 +1,18 @@
+package chconn
+
+import (
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+	"github.com/vahid-sohrabloo/chconn/setting"
+)
+
+// writeParameters writes the query parameters like the custom settings. The values are quoted and the server unquotes
+// them before parsing them as the type of the parameter.
+func writeParameters(w *readerwriter.Writer, parameters map[string]string) {
+	for name, value := range parameters {
+		w.String(name)
+		w.Uvarint(setting.FlagCustom)
+		w.String(setting.QuoteCustomValue(value))
+	}
+	// end of parameters
+	w.String("")
+}
@@ -0,0 +1,57 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+)
+
+func TestQueryParameters(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	stmt, err := conn.SelectWithOption(context.Background(),
+		`SELECT {str:String}, {num:UInt64}, {arr:Array(String)}`,
+		&QueryOptions{
+			Parameters: map[string]string{
+				"str": "it's a 'string'",
+				"num": "42",
+				"arr": "['a','b']",
+			},
+		})
+	require.NoError(t, err)
+	colStr := column.NewString(false)
+	colNum := column.NewUint64(false)
+	colArr := column.NewArray(column.NewString(false))
+	var strs []string
+	var nums []uint64
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(colStr))
+		colStr.ReadAllString(&strs)
+		require.NoError(t, stmt.NextColumn(colNum))
+		colNum.ReadAll(&nums)
+		require.NoError(t, stmt.NextColumn(colArr))
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	assert.Equal(t, []string{"it's a 'string'"}, strs)
+	assert.Equal(t, []uint64{42}, nums)
+
+	// wrong parameter type
+	res, err := conn.ExecWithOption(context.Background(), `SELECT {num:UInt64}`, &QueryOptions{
+		Parameters: map[string]string{
+			"num": "invalid",
+		},
+	})
+	require.Error(t, err)
+	require.Nil(t, res)
+}
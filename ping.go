@@ -8,23 +8,43 @@ type pong struct{}
 
 // Check that connection to the server is alive.
 func (ch *conn) Ping(ctx context.Context) error {
-	ch.contextWatcher.Watch(ctx)
+	err := ch.lock()
+	if err != nil {
+		return err
+	}
+	defer ch.unlock()
+
+	ch.watch(ctx)
 	defer ch.contextWatcher.Unwatch()
-	ch.writer.Uvarint(clientPing)
 	var hasError bool
 	defer func() {
 		if hasError {
 			ch.Close(context.Background())
 		}
 	}()
-	if _, err := ch.writer.WriteTo(ch.writerto); err != nil {
+	ch.writeMu.Lock()
+	if ch.canceled {
+		ch.writeMu.Unlock()
+		return ctx.Err()
+	}
+	ch.writer.Uvarint(clientPing)
+	_, err = ch.writer.WriteTo(ch.writerto)
+	ch.writeMu.Unlock()
+	if err != nil {
+		err = &writeError{"ping: write packet type", err}
+		if ch.isCanceled() {
+			return ch.finishCanceled(ctx, nil, err)
+		}
 		hasError = true
-		return &writeError{"ping: write packet type", err}
+		return err
 	}
 
 	res, err := ch.reciveAndProccessData(emptyOnProgress)
+	if ch.isCanceled() {
+		return ch.finishCanceled(ctx, res, err)
+	}
 	if err != nil {
-		hasError = true
+		hasError = needsClose(err)
 		return err
 	}
 	if _, ok := res.(*pong); !ok {
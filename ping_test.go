@@ -6,8 +6,10 @@ import (
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/internal/ctxwatch"
 )
 
 func TestPing(t *testing.T) {
@@ -59,3 +61,36 @@ func TestPingWriteError(t *testing.T) {
 
 	require.EqualError(t, c.Ping(context.Background()), "packet: read packet type (timeout)")
 }
+
+func TestPingCancel(t *testing.T) {
+	t.Parallel()
+
+	conn, err := Connect(context.Background(), startSilentServer(t))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	err = conn.Ping(ctx)
+	require.True(t, errors.Is(err, context.Canceled))
+	// nothing is in flight, so the connection is interrupted without waiting for CancelTimeout
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+	require.True(t, conn.IsClosed())
+}
+
+func TestPingBusy(t *testing.T) {
+	t.Parallel()
+
+	c := &conn{
+		status:         connStatusBusy,
+		contextWatcher: ctxwatch.NewContextWatcher(func() {}, func() {}),
+	}
+	// the context of an open select is watched until it is closed
+	c.contextWatcher.Watch(context.Background())
+	defer c.contextWatcher.Unwatch()
+	require.EqualError(t, c.Ping(context.Background()), "conn busy")
+	require.False(t, c.IsClosed())
+}
@@ -0,0 +1,159 @@
+package chconn
+
+import (
+	"time"
+
+	"github.com/vahid-sohrabloo/chconn/column"
+)
+
+// ProfileEventType is the type of a profile event
+type ProfileEventType int8
+
+const (
+	// ProfileEventIncrement is a counter. The value is the increment since the last packet
+	ProfileEventIncrement ProfileEventType = 1
+	// ProfileEventGauge is the current value of a metric (e.g. MemoryTrackerUsage)
+	ProfileEventGauge ProfileEventType = 2
+)
+
+// ProfileEvent is a counter of the query execution on the server (e.g. SelectedRows or ReadCompressedBytes).
+type ProfileEvent struct {
+	Host string
+	Time time.Time
+	// ThreadID is the thread that the event belongs to. Zero means the event is the total of the query
+	ThreadID uint64
+	Type     ProfileEventType
+	Name     string
+	Value    int64
+}
+
+// ProfileEvents is the aggregated profile events of a query by the name of the event.
+type ProfileEvents map[string]int64
+
+// Add aggregates the events of a ProfileEvents packet. The increments are summed and the gauges keep the last value.
+// Only the totals of the query (zero ThreadID) are aggregated because the server also sends the events of each thread.
+func (p ProfileEvents) Add(events []ProfileEvent) {
+	for _, e := range events {
+		if e.ThreadID != 0 {
+			continue
+		}
+		if e.Type == ProfileEventGauge {
+			p[e.Name] = e.Value
+			continue
+		}
+		p[e.Name] += e.Value
+	}
+}
+
+// readProfileEvents reads the columns of a profile events block.
+func readProfileEvents(ch *conn, block *Block) ([]ProfileEvent, error) { //nolint:gocyclo
+	numRows := int(block.NumRows)
+	events := make([]ProfileEvent, numRows)
+	for i := uint64(0); i < block.NumColumns; i++ {
+		col, err := block.nextColumn(ch)
+		if err != nil {
+			return nil, err
+		}
+		if numRows == 0 {
+			continue
+		}
+		switch col.Name {
+		case "host_name":
+			colHost := column.NewString(false)
+			if err := colHost.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"profile events: read " + col.Name, err}
+			}
+			for j := 0; colHost.Next(); j++ {
+				events[j].Host = colHost.ValueString()
+			}
+		case "current_time":
+			colTime := column.NewUint32(false)
+			if err := colTime.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"profile events: read " + col.Name, err}
+			}
+			for j := 0; colTime.Next(); j++ {
+				events[j].Time = time.Unix(int64(colTime.Value()), 0)
+			}
+		case "thread_id":
+			colThreadID := column.NewUint64(false)
+			if err := colThreadID.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"profile events: read " + col.Name, err}
+			}
+			for j := 0; colThreadID.Next(); j++ {
+				events[j].ThreadID = colThreadID.Value()
+			}
+		case "type":
+			colType := column.NewInt8(false)
+			if err := colType.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"profile events: read " + col.Name, err}
+			}
+			for j := 0; colType.Next(); j++ {
+				events[j].Type = ProfileEventType(colType.Value())
+			}
+		case "name":
+			if err := readProfileEventNames(ch, col, events); err != nil {
+				return nil, err
+			}
+		case "value":
+			colValue := column.NewInt64(false)
+			if err := colValue.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"profile events: read " + col.Name, err}
+			}
+			for j := 0; colValue.Next(); j++ {
+				events[j].Value = colValue.Value()
+			}
+		default:
+			if err := skipColumn(ch.reader, col.ChType, numRows); err != nil {
+				return nil, &readError{"profile events: skip column " + col.Name, err}
+			}
+		}
+	}
+	return events, nil
+}
+
+// readProfileEventNames reads the name column which is LowCardinality(String) in the newer servers.
+func readProfileEventNames(ch *conn, col *Column, events []ProfileEvent) error {
+	colName := column.NewString(false)
+	if name, _ := splitChType(col.ChType); name != "LowCardinality" {
+		if err := colName.ReadRaw(len(events), ch.reader); err != nil {
+			return &readError{"profile events: read " + col.Name, err}
+		}
+		for j := 0; colName.Next(); j++ {
+			events[j].Name = colName.ValueString()
+		}
+		return nil
+	}
+
+	colNameLC := column.NewLC(colName)
+	if err := colNameLC.HeaderReader(ch.reader); err != nil {
+		return &readError{"profile events: read " + col.Name + " header", err}
+	}
+	if err := colNameLC.ReadRaw(len(events), ch.reader); err != nil {
+		return &readError{"profile events: read " + col.Name, err}
+	}
+	var dict []string
+	colName.ReadAllString(&dict)
+	for j := 0; colNameLC.Next() && j < len(events); j++ {
+		if key := colNameLC.Value(); key < len(dict) {
+			events[j].Name = dict[key]
+		}
+	}
+	return nil
+}
+
+// readProfileEvents reads a profile events packet and passes the events to the callback of the query.
+func (ch *conn) readProfileEvents() error {
+	block := newBlock()
+	if err := block.readUncompressed(ch); err != nil {
+		return err
+	}
+	if ch.onProfileEvents == nil {
+		return block.skipColumns(ch)
+	}
+	events, err := readProfileEvents(ch, block)
+	if err != nil {
+		return err
+	}
+	ch.onProfileEvents(events)
+	return nil
+}
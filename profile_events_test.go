@@ -0,0 +1,57 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+)
+
+func TestProfileEventsAdd(t *testing.T) {
+	events := ProfileEvents{}
+	events.Add([]ProfileEvent{
+		{Name: "SelectedRows", Type: ProfileEventIncrement, Value: 10},
+		{Name: "SelectedRows", Type: ProfileEventIncrement, Value: 7, ThreadID: 12},
+		{Name: "MemoryTrackerUsage", Type: ProfileEventGauge, Value: 100},
+	})
+	events.Add([]ProfileEvent{
+		{Name: "SelectedRows", Type: ProfileEventIncrement, Value: 5},
+		{Name: "MemoryTrackerUsage", Type: ProfileEventGauge, Value: 80},
+	})
+	assert.Equal(t, ProfileEvents{
+		"SelectedRows":       15,
+		"MemoryTrackerUsage": 80,
+	}, events)
+}
+
+func TestProfileEvents(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	if conn.ServerInfo().Revision < dbmsMinProtocolVersionWithIncrementalProfileEvents {
+		t.Skip("server does not send profile events")
+	}
+
+	var packets int
+	stmt, err := conn.SelectWithOption(context.Background(), `SELECT number FROM system.numbers LIMIT 100000`, &QueryOptions{
+		OnProfileEvents: func(events []ProfileEvent) {
+			packets++
+		},
+	})
+	require.NoError(t, err)
+	col := column.NewUint64(false)
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(col))
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+
+	assert.NotZero(t, packets)
+	assert.NotZero(t, stmt.ProfileEvents()["SelectedRows"])
+}
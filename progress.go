@@ -1,12 +1,16 @@
 package chconn
 
+import "time"
+
 // Profile details of progress select query
 type Progress struct {
 	ReadRows     uint64
 	Readbytes    uint64
 	TotalRows    uint64
+	TotalBytes   uint64
 	WriterRows   uint64
 	WrittenBytes uint64
+	Elapsed      time.Duration
 }
 
 func newProgress() *Progress {
@@ -25,6 +29,12 @@ func (p *Progress) read(ch *conn) (err error) {
 		return &readError{"progress: read TotalRows", err}
 	}
 
+	if ch.serverInfo.Revision >= dbmsMinProtocolVersionWithTotalBytesInProgress {
+		if p.TotalBytes, err = ch.reader.Uvarint(); err != nil {
+			return &readError{"progress: read TotalBytes", err}
+		}
+	}
+
 	if ch.serverInfo.Revision >= dbmsMinRevisionWithClientWriteInfo {
 		if p.WriterRows, err = ch.reader.Uvarint(); err != nil {
 			return &readError{"progress: read WriterRows", err}
@@ -34,5 +44,13 @@ func (p *Progress) read(ch *conn) (err error) {
 		}
 	}
 
+	if ch.serverInfo.Revision >= dbmsMinProtocolVersionWithServerQueryTimeInProgress {
+		var elapsed uint64
+		if elapsed, err = ch.reader.Uvarint(); err != nil {
+			return &readError{"progress: read Elapsed", err}
+		}
+		p.Elapsed = time.Duration(elapsed)
+	}
+
 	return nil
 }
@@ -0,0 +1,78 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/setting"
+)
+
+func TestQueryOptionsClientInfo(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	// the options can be nil
+	_, err = conn.ExecWithOption(context.Background(), "SELECT 1", nil)
+	require.NoError(t, err)
+
+	stmt, err := conn.SelectWithOption(context.Background(),
+		`SELECT client_name, os_user, client_hostname, quota_key FROM system.processes
+			WHERE query_id = 'chconn_test_query_options'`,
+		&QueryOptions{
+			QueryID:        "chconn_test_query_options",
+			QuotaKey:       "test_quota_key",
+			ClientName:     "test_client",
+			OSUser:         "test_user",
+			ClientHostname: "test_host",
+		})
+	require.NoError(t, err)
+	colClientName := column.NewString(false)
+	colOSUser := column.NewString(false)
+	colClientHostname := column.NewString(false)
+	colQuotaKey := column.NewString(false)
+	var clientNames, osUsers, clientHostnames, quotaKeys []string
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(colClientName))
+		colClientName.ReadAllString(&clientNames)
+		require.NoError(t, stmt.NextColumn(colOSUser))
+		colOSUser.ReadAllString(&osUsers)
+		require.NoError(t, stmt.NextColumn(colClientHostname))
+		colClientHostname.ReadAllString(&clientHostnames)
+		require.NoError(t, stmt.NextColumn(colQuotaKey))
+		colQuotaKey.ReadAllString(&quotaKeys)
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	require.Equal(t, []string{"test_client"}, clientNames)
+	require.Equal(t, []string{"test_user"}, osUsers)
+	require.Equal(t, []string{"test_host"}, clientHostnames)
+	require.Equal(t, []string{"test_quota_key"}, quotaKeys)
+
+	stmt, err = conn.SelectWithOption(context.Background(), "SELECT 1", nil)
+	require.NoError(t, err)
+	for stmt.Next() {
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+}
+
+func TestCheckQueryOptionsCustomSettings(t *testing.T) {
+	t.Parallel()
+
+	settings := setting.NewSettings()
+	settings.Custom("custom_name", "value")
+	c := &conn{}
+	c.serverInfo.Revision = dbmsMinRevisionWithSettingsSerializedAsStrings - 1
+	require.Equal(t, ErrCustomSettingsNotSupported, c.checkQueryOptions(&QueryOptions{Settings: settings}))
+
+	c.serverInfo.Revision = dbmsMinRevisionWithSettingsSerializedAsStrings
+	require.NoError(t, c.checkQueryOptions(&QueryOptions{Settings: settings}))
+}
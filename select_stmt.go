@@ -8,22 +8,32 @@ import (
 
 type SelectStmt interface {
 	// Next get the next block, if available return true else return false
-	// if the server sends an error return false and we can get the last error with Err() function
+	// if the server sends an error return false and we can get the last error with Err() function.
+	// The totals and the extremes are returned as blocks too. Block().Kind is the kind of the block
+	// The context of the query is watched until Close, so if it is canceled the query is canceled and Err returns the
+	// context error
 	Next() bool
 	// Err When calls Next() func if server send error we can get error from thhis function
 	Err() error
 	// RowsInBlock return number of rows in this current block
 	RowsInBlock() int
-	// Close after reads all data should call this function to unlock connection
-	// NOTE: You shoud read all data and then call this function
+	// Close unlocks the connection. If the result is not read to the end, the query is canceled and the rest of the
+	// result is discarded in the limits of Config.CloseDrainTimeout and Config.CloseDrainMaxBytes. If that is not
+	// possible the connection is closed and Err returns ErrDrainLimit
 	Close()
 	// ‌Block get current block
 	// NOTE: Never use this if you do not know what a block is
 	Block() *Block
-	// NextColumn get the next column of block
+	// NextColumn get the next column of block. The name and the type of the column are added to Block().Columns
+	// (e.g. to check the AggregateFunction type of the columns of a query with the WithMergeableState stage)
 	NextColumn(colData column.Column) error
+	// ProfileEvents get the aggregated profile events of the query that are received so far.
+	// NOTE: The server sends the final values at the end of the query
+	ProfileEvents() ProfileEvents
 }
 type selectStmt struct {
+	// ctx is the context of the query. It is watched until the stmt is closed
+	ctx              context.Context
 	block            *Block
 	conn             *conn
 	query            string
@@ -31,6 +41,8 @@ type selectStmt struct {
 	clientInfo       *ClientInfo
 	onProgress       func(*Progress)
 	onProfile        func(*Profile)
+	onProfileEvents  func([]ProfileEvent)
+	profileEvents    ProfileEvents
 	lastErr          error
 	ProfileInfo      *Profile
 	Progress         *Progress
@@ -41,7 +53,8 @@ type selectStmt struct {
 var _ SelectStmt = &selectStmt{}
 
 // Next get the next block, if available return true else return false
-// if the server sends an error return false and we can get the last error with Err() function
+// if the server sends an error return false and we can get the last error with Err() function.
+// The totals and the extremes are returned as blocks too. Block().Kind is the kind of the block
 func (s *selectStmt) Next() bool {
 	if s.lastErr == nil &&
 		s.block != nil &&
@@ -55,6 +68,10 @@ func (s *selectStmt) Next() bool {
 
 	s.conn.reader.SetCompress(false)
 	res, err := s.conn.reciveAndProccessData(nil)
+	if s.conn.isCanceled() {
+		s.lastErr = s.conn.finishCanceled(s.ctx, res, err)
+		return false
+	}
 	if err != nil {
 		s.lastErr = err
 		return false
@@ -113,20 +130,49 @@ func (s *selectStmt) Err() error {
 	return s.lastErr
 }
 
-// Close after reads all data should call this function to unlock connection
-// NOTE: You shoud read all data and then call this function
+// Close unlocks the connection. If the result is not read to the end, the query is canceled and the rest of the
+// result is discarded in the limits of Config.CloseDrainTimeout and Config.CloseDrainMaxBytes. If that is not possible
+// the connection is closed and Err returns ErrDrainLimit
 func (s *selectStmt) Close() {
-	s.conn.reader.SetCompress(false)
 	if !s.closed {
 		s.closed = true
+		if s.lastErr == nil && s.conn.isInFlight() {
+			s.lastErr = s.discard()
+		}
+		s.conn.contextWatcher.Unwatch()
 		s.conn.unlock()
-		if s.Err() != nil {
+		if s.Err() != nil && needsClose(s.Err()) {
 			s.conn.Close(context.Background())
 		}
 	}
+	s.conn.reader.SetCompress(false)
 	s.numberColumnRead = 0
 }
 
+// discard cancels the unfinished query and discards the rest of the current block and the result.
+func (s *selectStmt) discard() error {
+	if s.block != nil && s.numberColumnRead < int(s.block.NumColumns) {
+		if err := s.block.skipColumnsFrom(s.conn, uint64(s.numberColumnRead)); err != nil {
+			return err
+		}
+	}
+	s.conn.reader.SetCompress(false)
+	return s.conn.discard()
+}
+
+// ProfileEvents get the aggregated profile events of the query that are received so far.
+// NOTE: The server sends the final values at the end of the query
+func (s *selectStmt) ProfileEvents() ProfileEvents {
+	return s.profileEvents
+}
+
+func (s *selectStmt) addProfileEvents(events []ProfileEvent) {
+	s.profileEvents.Add(events)
+	if s.onProfileEvents != nil {
+		s.onProfileEvents(events)
+	}
+}
+
 // NextColumn get the next column of block
 func (s *selectStmt) NextColumn(colData column.Column) error {
 	s.numberColumnRead++
@@ -136,22 +182,30 @@ func (s *selectStmt) NextColumn(colData column.Column) error {
 			Available: s.block.NumColumns,
 		}
 	}
-	_, err := s.block.nextColumn(s.conn)
+	col, err := s.block.nextColumn(s.conn)
 	if err != nil {
-		s.Close()
-		s.conn.Close(context.Background())
-		return err
+		return s.closeWithError(err)
 	}
+	s.block.Columns = append(s.block.Columns, col)
 	err = colData.HeaderReader(s.conn.reader)
 	if err != nil {
-		s.Close()
-		s.conn.Close(context.Background())
-		return err
+		return s.closeWithError(err)
 	}
 	err = colData.ReadRaw(s.RowsInBlock(), s.conn.reader)
 	if err != nil {
-		s.Close()
-		s.conn.Close(context.Background())
+		return s.closeWithError(err)
+	}
+	return nil
+}
+
+// closeWithError closes the stmt and the connection after an error in the middle of a block. It returns the context
+// error if the query was canceled, because the error is caused by the interrupted connection
+func (s *selectStmt) closeWithError(err error) error {
+	canceled := s.conn.isCanceled()
+	s.lastErr = err
+	s.Close()
+	if canceled {
+		return s.ctx.Err()
 	}
 	return err
 }
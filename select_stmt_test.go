@@ -4,11 +4,18 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/internal/ctxwatch"
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+	"github.com/vahid-sohrabloo/chconn/setting"
 )
 
 func TestSelectError(t *testing.T) {
@@ -80,3 +87,240 @@ func TestSelectError(t *testing.T) {
 
 	c.Close(context.Background())
 }
+
+func TestSelectStage(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	// the state of sum(UInt64) is the UInt64 sum
+	res, err := conn.SelectWithOption(context.Background(), "SELECT sum(number) FROM numbers(10)", &QueryOptions{
+		Stage: QueryProcessingStageWithMergeableState,
+	})
+	require.NoError(t, err)
+	col := column.NewUint64(false)
+	var colData []uint64
+	for res.Next() {
+		require.NoError(t, res.NextColumn(col))
+		require.Len(t, res.Block().Columns, 1)
+		require.Equal(t, "AggregateFunction(sum, UInt64)", res.Block().Columns[0].ChType)
+		col.ReadAll(&colData)
+	}
+	require.NoError(t, res.Err())
+	res.Close()
+	require.Equal(t, []uint64{45}, colData)
+
+	// the expressions are not calculated in the FetchColumns stage
+	res, err = conn.SelectWithOption(context.Background(), "SELECT number * 2 FROM numbers(3)", &QueryOptions{
+		Stage: QueryProcessingStageFetchColumns,
+	})
+	require.NoError(t, err)
+	colData = colData[:0]
+	for res.Next() {
+		require.NoError(t, res.NextColumn(col))
+		require.Equal(t, "number", res.Block().Columns[0].Name)
+		col.ReadAll(&colData)
+	}
+	require.NoError(t, res.Err())
+	res.Close()
+	require.Equal(t, []uint64{0, 1, 2}, colData)
+}
+
+func TestQueryProcessingStageProtocolValue(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, uint64(2), QueryProcessingStageComplete.protocolValue())
+	require.Equal(t, uint64(0), QueryProcessingStageFetchColumns.protocolValue())
+	require.Equal(t, uint64(1), QueryProcessingStageWithMergeableState.protocolValue())
+	require.Equal(t, uint64(3), QueryProcessingStageWithMergeableStateAfterAggregation.protocolValue())
+}
+
+func TestSelectCloseUnfinished(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	config, err := ParseConfig(connString)
+	require.NoError(t, err)
+	conn, err := ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	// the query is canceled and the rest of the result is discarded
+	stmt, err := conn.Select(context.Background(), `SELECT number, toString(number) FROM system.numbers`)
+	require.NoError(t, err)
+	require.True(t, stmt.Next())
+	require.NoError(t, stmt.NextColumn(column.NewUint64(false)))
+	stmt.Close()
+	require.NoError(t, stmt.Err())
+	require.False(t, conn.IsClosed())
+
+	res, err := conn.Exec(context.Background(), `SELECT 1`)
+	require.NoError(t, err)
+	require.NotNil(t, res)
+
+	// the connection is closed if the result can not be discarded
+	config.CloseDrainTimeout = 0
+	conn, err = ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	stmt, err = conn.Select(context.Background(), `SELECT number FROM system.numbers`)
+	require.NoError(t, err)
+	require.True(t, stmt.Next())
+	stmt.Close()
+	require.True(t, errors.Is(stmt.Err(), ErrDrainLimit))
+	require.True(t, conn.IsClosed())
+}
+
+func TestTotalsAndExtremes(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	settings := setting.NewSettings()
+	settings.Extremes(true)
+	stmt, err := conn.SelectWithSetting(context.Background(), `SELECT
+		number % 3 AS k,
+		count() AS c
+	FROM system.numbers_mt
+	WHERE number < 10
+	GROUP BY k
+	ORDER BY k
+	WITH TOTALS`, settings)
+	require.NoError(t, err)
+
+	colK := column.NewUint8(false)
+	colC := column.NewUint64(false)
+	var counts, totals []uint64
+	var extremes []uint8
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(colK))
+		require.NoError(t, stmt.NextColumn(colC))
+		switch stmt.Block().Kind {
+		case BlockData:
+			colC.ReadAll(&counts)
+		case BlockTotals:
+			colC.ReadAll(&totals)
+		case BlockExtremes:
+			colK.ReadAll(&extremes)
+		}
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	assert.Equal(t, []uint64{4, 3, 3}, counts)
+	assert.Equal(t, []uint64{10}, totals)
+	assert.Equal(t, []uint8{0, 2}, extremes)
+}
+
+func TestSelectBlockKind(t *testing.T) {
+	t.Parallel()
+
+	w := readerwriter.NewWriter()
+	writeBlock := func(packet uint64, info blockInfo, value uint8) {
+		w.Uvarint(packet)
+		w.String("")
+		info.write(w)
+		w.Uvarint(1)
+		w.Uvarint(1)
+		w.String("k")
+		w.String("UInt8")
+		w.Uint8(value)
+	}
+	writeBlock(serverData, blockInfo{bucketNum: 3}, 1)
+	writeBlock(serverTotals, blockInfo{isOverflows: true}, 2)
+	writeBlock(serverExtremes, blockInfo{}, 3)
+	w.Uvarint(serverEndOfStream)
+
+	client, server := net.Pipe()
+	defer server.Close()
+	c := &conn{
+		conn:           client,
+		config:         &Config{},
+		status:         connStatusBusy,
+		inFlight:       true,
+		reader:         readerwriter.NewReader(w.Output()),
+		contextWatcher: ctxwatch.NewContextWatcher(func() {}, func() {}),
+	}
+	stmt := &selectStmt{ctx: context.Background(), conn: c}
+
+	col := column.NewUint8(false)
+	var kinds []BlockKind
+	var values []uint8
+	var overflows []bool
+	var buckets []int32
+	for stmt.Next() {
+		kinds = append(kinds, stmt.Block().Kind)
+		overflows = append(overflows, stmt.Block().IsOverflows())
+		buckets = append(buckets, stmt.Block().BucketNum())
+		require.NoError(t, stmt.NextColumn(col))
+		col.ReadAll(&values)
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	require.False(t, c.IsClosed())
+	assert.Equal(t, []BlockKind{BlockData, BlockTotals, BlockExtremes}, kinds)
+	assert.Equal(t, []uint8{1, 2, 3}, values)
+	assert.Equal(t, []bool{false, true, false}, overflows)
+	assert.Equal(t, []int32{3, -1, -1}, buckets)
+}
+
+func TestSelectCloseDrain(t *testing.T) {
+	t.Parallel()
+
+	newStmt := func(config *Config) (*selectStmt, *conn) {
+		w := readerwriter.NewWriter()
+		for i := 0; i < 3; i++ {
+			w.Uvarint(serverData)
+			w.String("")
+			(&blockInfo{}).write(w)
+			w.Uvarint(2)
+			w.Uvarint(100)
+			w.String("a")
+			w.String("UInt64")
+			w.Write(make([]byte, 100*8))
+			w.String("b")
+			w.String("UInt64")
+			w.Write(make([]byte, 100*8))
+		}
+		w.Uvarint(serverEndOfStream)
+
+		client, server := net.Pipe()
+		t.Cleanup(func() {
+			server.Close()
+		})
+		c := &conn{
+			conn:           client,
+			config:         config,
+			status:         connStatusBusy,
+			inFlight:       true,
+			readCounter:    &readCounter{r: w.Output()},
+			writerto:       ioutil.Discard,
+			contextWatcher: ctxwatch.NewContextWatcher(func() {}, func() {}),
+		}
+		c.reader = readerwriter.NewReader(c.readCounter)
+		return &selectStmt{ctx: context.Background(), conn: c}, c
+	}
+
+	// an early break discards the rest of the result and keeps the connection
+	stmt, c := newStmt(&Config{CloseDrainTimeout: time.Minute})
+	require.True(t, stmt.Next())
+	require.NoError(t, stmt.NextColumn(column.NewUint64(false)))
+	stmt.Close()
+	require.NoError(t, stmt.Err())
+	require.False(t, c.IsClosed())
+	require.False(t, c.isInFlight())
+
+	// the connection is closed if the result can not be discarded in the limits
+	stmt, c = newStmt(&Config{CloseDrainTimeout: time.Minute, CloseDrainMaxBytes: 1000})
+	require.True(t, stmt.Next())
+	stmt.Close()
+	require.True(t, errors.Is(stmt.Err(), ErrDrainLimit))
+	require.True(t, c.IsClosed())
+}
@@ -16,6 +16,21 @@ type ServerInfo struct {
 	ServerDisplayName  string
 	ServerVersionPatch uint64
 	Timezone           *time.Location
+	// SessionTimezone is the timezone of the last query if the session_timezone setting is set
+	SessionTimezone *time.Location
+	// PasswordComplexityRules are the rules that the server checks for the new passwords
+	PasswordComplexityRules []PasswordComplexityRule
+
+	// nonce is used for the interserver secret
+	nonce uint64
+}
+
+// PasswordComplexityRule is a rule of the server for the new passwords
+type PasswordComplexityRule struct {
+	// Pattern is a regular expression that the password must match
+	Pattern string
+	// Message is the error message if the password doesn't match the pattern
+	Message string
 }
 
 func (srv *ServerInfo) read(r *readerwriter.Reader) (err error) {
@@ -51,6 +66,26 @@ func (srv *ServerInfo) read(r *readerwriter.Reader) (err error) {
 			return &readError{"ServerInfo: could not read server version patch", err}
 		}
 	}
+	if srv.Revision >= dbmsMinProtocolVersionWithPasswordComplexityRules {
+		var numRules uint64
+		if numRules, err = r.Uvarint(); err != nil {
+			return &readError{"ServerInfo: could not read password complexity rules", err}
+		}
+		srv.PasswordComplexityRules = make([]PasswordComplexityRule, numRules)
+		for i := range srv.PasswordComplexityRules {
+			if srv.PasswordComplexityRules[i].Pattern, err = r.String(); err != nil {
+				return &readError{"ServerInfo: could not read password complexity pattern", err}
+			}
+			if srv.PasswordComplexityRules[i].Message, err = r.String(); err != nil {
+				return &readError{"ServerInfo: could not read password complexity message", err}
+			}
+		}
+	}
+	if srv.Revision >= dbmsMinRevisionWithInterserverSecretV2 {
+		if srv.nonce, err = r.Uint64(); err != nil {
+			return &readError{"ServerInfo: could not read nonce", err}
+		}
+	}
 	return nil
 }
 
@@ -46,6 +46,14 @@ func TestServerInfoError(t *testing.T) {
 			name:        "server version patch",
 			wantErr:     "ServerInfo: could not read server version patch",
 			numberValid: startValidReader + 11,
+		}, {
+			name:        "password complexity rules",
+			wantErr:     "ServerInfo: could not read password complexity rules",
+			numberValid: startValidReader + 12,
+		}, {
+			name:        "nonce",
+			wantErr:     "ServerInfo: could not read nonce",
+			numberValid: startValidReader + 13,
 		},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,116 @@
+package chconn
+
+import (
+	"time"
+
+	"github.com/vahid-sohrabloo/chconn/column"
+)
+
+// ServerLog is a log of the query execution on the server.
+// The server only sends the logs if the send_logs_level setting is set.
+type ServerLog struct {
+	Time     time.Time
+	Host     string
+	QueryID  string
+	ThreadID uint64
+	// Priority is the level of the log: 1 Fatal, 2 Critical, 3 Error, 4 Warning, 5 Notice, 6 Information, 7 Debug,
+	// 8 Trace and 9 Test
+	Priority int8
+	Source   string
+	Text     string
+}
+
+// readServerLogs reads the columns of a log block.
+func readServerLogs(ch *conn, block *Block) ([]ServerLog, error) {
+	numRows := int(block.NumRows)
+	logs := make([]ServerLog, numRows)
+	var (
+		seconds      []uint32
+		microseconds []uint32
+	)
+	for i := uint64(0); i < block.NumColumns; i++ {
+		col, err := block.nextColumn(ch)
+		if err != nil {
+			return nil, err
+		}
+		switch col.Name {
+		case "event_time", "event_time_microseconds":
+			colTime := column.NewUint32(false)
+			if err := colTime.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"server log: read " + col.Name, err}
+			}
+			if col.Name == "event_time" {
+				colTime.ReadAll(&seconds)
+			} else {
+				colTime.ReadAll(&microseconds)
+			}
+		case "host_name", "query_id", "source", "text":
+			colString := column.NewString(false)
+			if err := colString.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"server log: read " + col.Name, err}
+			}
+			for j := 0; colString.Next(); j++ {
+				switch col.Name {
+				case "host_name":
+					logs[j].Host = colString.ValueString()
+				case "query_id":
+					logs[j].QueryID = colString.ValueString()
+				case "source":
+					logs[j].Source = colString.ValueString()
+				default:
+					logs[j].Text = colString.ValueString()
+				}
+			}
+		case "thread_id":
+			colThreadID := column.NewUint64(false)
+			if err := colThreadID.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"server log: read " + col.Name, err}
+			}
+			for j := 0; colThreadID.Next(); j++ {
+				logs[j].ThreadID = colThreadID.Value()
+			}
+		case "priority":
+			colPriority := column.NewInt8(false)
+			if err := colPriority.ReadRaw(numRows, ch.reader); err != nil {
+				return nil, &readError{"server log: read " + col.Name, err}
+			}
+			for j := 0; colPriority.Next(); j++ {
+				logs[j].Priority = colPriority.Value()
+			}
+		default:
+			if err := skipColumn(ch.reader, col.ChType, numRows); err != nil {
+				return nil, &readError{"server log: skip column " + col.Name, err}
+			}
+		}
+	}
+	for i := range logs {
+		var sec, usec int64
+		if i < len(seconds) {
+			sec = int64(seconds[i])
+		}
+		if i < len(microseconds) {
+			usec = int64(microseconds[i])
+		}
+		logs[i].Time = time.Unix(sec, usec*int64(time.Microsecond))
+	}
+	return logs, nil
+}
+
+// readServerLog reads a log packet and passes the logs to the OnLog callback of the query.
+func (ch *conn) readServerLog() error {
+	block := newBlock()
+	if err := block.readUncompressed(ch); err != nil {
+		return err
+	}
+	if ch.onLog == nil {
+		return block.skipColumns(ch)
+	}
+	logs, err := readServerLogs(ch, block)
+	if err != nil {
+		return err
+	}
+	for i := range logs {
+		ch.onLog(&logs[i])
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/setting"
+)
+
+func TestServerLog(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+
+	settings := setting.NewSettings()
+	settings.SendLogsLevel("trace")
+	var logs []*ServerLog
+	stmt, err := conn.SelectWithOption(context.Background(), `SELECT number FROM system.numbers LIMIT 10`, &QueryOptions{
+		QueryID:  "test_server_log",
+		Settings: settings,
+		OnLog: func(l *ServerLog) {
+			logs = append(logs, l)
+		},
+	})
+	require.NoError(t, err)
+	col := column.NewUint64(false)
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(col))
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+
+	require.NotEmpty(t, logs)
+	for _, l := range logs {
+		assert.Equal(t, "test_server_log", l.QueryID)
+		assert.NotEmpty(t, l.Text)
+		assert.False(t, l.Time.IsZero())
+	}
+
+	// the logs must be skipped without callback
+	_, err = conn.ExecWithOption(context.Background(), `SELECT 1`, &QueryOptions{
+		Settings: settings,
+	})
+	require.NoError(t, err)
+}
@@ -3,6 +3,7 @@ package setting
 import (
 	"io"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
@@ -15,8 +16,28 @@ import (
 // Because we use native TCP connections and some setting only use for HTTP connections
 type Settings struct {
 	configs map[string]interface{}
+	flags   map[string]uint64
 	dirty   bool
-	w       *readerwriter.Writer
+	// asString is the format of the settings in w
+	asString bool
+	w        *readerwriter.Writer
+}
+
+const (
+	// flagImportant the server returns an error if it doesn't know the setting instead of ignoring it
+	flagImportant = 0x01
+	// FlagCustom the setting is a custom setting (e.g. custom_foo). The query parameters are sent as custom settings too
+	FlagCustom = 0x02
+)
+
+// customValue is the value of a custom setting. the server parses custom setting values as ClickHouse literals
+type customValue string
+
+var customValueQuoter = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// QuoteCustomValue quotes the value of a custom setting (or a query parameter) as a ClickHouse string literal
+func QuoteCustomValue(value string) string {
+	return "'" + customValueQuoter.Replace(value) + "'"
 }
 
 // NewSettings return new settings for clickhouse query setting
@@ -25,43 +46,126 @@ type Settings struct {
 func NewSettings() *Settings {
 	return &Settings{
 		configs: make(map[string]interface{}),
+		flags:   make(map[string]uint64),
 		w:       readerwriter.NewWriter(),
 	}
 }
 
+// Important marks the setting as important. The server returns an error for the important settings that it doesn't
+// know instead of ignoring them.
+func (s *Settings) Important(name string) {
+	s.flags[name] |= flagImportant
+	s.dirty = true
+}
+
+// Custom set a custom setting (the name must start with one of custom_settings_prefixes of the server config)
+func (s *Settings) Custom(name, value string) {
+	s.configs[name] = customValue(value)
+	s.flags[name] |= FlagCustom
+	s.dirty = true
+}
+
+// Clone returns a copy of the settings
+func (s *Settings) Clone() *Settings {
+	c := NewSettings()
+	for k, v := range s.configs {
+		c.configs[k] = v
+	}
+	for k, v := range s.flags {
+		c.flags[k] = v
+	}
+	c.dirty = true
+	return c
+}
+
+// Get get the value of a setting that is set in the settings
+func (s *Settings) Get(name string) (interface{}, bool) {
+	v, ok := s.configs[name]
+	return v, ok
+}
+
+// HasCustom reports if there is a custom setting. The custom settings are only supported by the servers that get the
+// settings as strings
+func (s *Settings) HasCustom() bool {
+	for _, v := range s.configs {
+		if _, ok := v.(customValue); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTo writes the settings. If asString is false (the servers before the settings are serialized as strings) the
+// settings are written as binary values without the flags. These servers always return an error for the unknown
+// settings, so the important flag is not needed, and they don't support the custom settings
 func (s *Settings) WriteTo(wt io.Writer, asString bool) (int, error) {
-	// todo handle asString for old protocols
-	if s.dirty {
+	if s.dirty || s.asString != asString {
 		s.w.Reset()
 		s.dirty = false
+		s.asString = asString
 		for key, v := range s.configs {
 			s.w.String(key)
-			// flag
-			s.w.Uint8(0)
-			switch val := v.(type) {
-			case uint64:
-				s.w.String(strconv.FormatUint(val, 10))
-			case int64:
-				s.w.String(strconv.FormatInt(val, 10))
-			case string:
-				s.w.String(val)
-			case bool:
-				if val {
-					s.w.String("1")
-				} else {
-					s.w.String("0")
-				}
-
-			case byte:
-				s.w.String(string(val))
-			default:
-				panic("not support type")
+			if asString {
+				// flag
+				s.w.Uvarint(s.flags[key])
+				s.writeString(v)
+			} else {
+				s.writeBinary(v)
 			}
 		}
 	}
 	return wt.Write(s.w.Output().Bytes())
 }
 
+func (s *Settings) writeString(v interface{}) {
+	switch val := v.(type) {
+	case uint64:
+		s.w.String(strconv.FormatUint(val, 10))
+	case int64:
+		s.w.String(strconv.FormatInt(val, 10))
+	case string:
+		s.w.String(val)
+	case customValue:
+		s.w.String(QuoteCustomValue(string(val)))
+	case bool:
+		if val {
+			s.w.String("1")
+		} else {
+			s.w.String("0")
+		}
+
+	case byte:
+		s.w.String(string(val))
+	default:
+		panic("not support type")
+	}
+}
+
+// writeBinary writes the value in the format of the servers before the settings are serialized as strings.
+// The float, enum and char settings are strings in this format too
+func (s *Settings) writeBinary(v interface{}) {
+	switch val := v.(type) {
+	case uint64:
+		s.w.Uvarint(val)
+	case int64:
+		s.w.Varint(val)
+	case string:
+		s.w.String(val)
+	case customValue:
+		s.w.String(QuoteCustomValue(string(val)))
+	case bool:
+		if val {
+			s.w.Uvarint(1)
+		} else {
+			s.w.Uvarint(0)
+		}
+	case byte:
+		s.w.String(string(val))
+	default:
+		panic("not support type")
+	}
+}
+
 // MinCompressBlockSize set min_compress_block_size setting
 // The actual size of the block to compress, if the uncompressed data less than
 // max_compress_block_size is no less than this value and no less than the volume
@@ -679,6 +783,14 @@ func (s *Settings) LogQueries(v bool) {
 	s.dirty = true
 }
 
+// SendLogsLevel set send_logs_level setting
+// Send server text logs with specified minimum level to client. Valid values:
+// 'trace', 'debug', 'information', 'warning', 'error', 'fatal', 'none'
+func (s *Settings) SendLogsLevel(v string) {
+	s.configs["send_logs_level"] = v
+	s.dirty = true
+}
+
 // LogQueriesCutToLength set log_queries_cut_to_length setting
 // If query length is greater than specified threshold (in bytes), then cut query
 // when writing to query log. Also limit length of printed query in ordinary text
@@ -703,6 +815,13 @@ func (s *Settings) InsertDeduplicate(v bool) {
 	s.dirty = true
 }
 
+// InsertDeduplicationToken set insert_deduplication_token setting
+// If not empty, used for duplicate detection instead of data digest
+func (s *Settings) InsertDeduplicationToken(v string) {
+	s.configs["insert_deduplication_token"] = v
+	s.dirty = true
+}
+
 // InsertQuorum set insert_quorum setting
 // For INSERT queries in the replicated table, wait writing for the specified
 // number of replicas and linearize the addition of the data. 0 - disabled.
@@ -1048,6 +1167,13 @@ func (s *Settings) MaxExecutionTime(v time.Duration) {
 	s.dirty = true
 }
 
+// TimeoutOverflowMode set timeout_overflow_mode setting
+// What to do when max_execution_time is exceeded. Valid values: 'throw', 'break'
+func (s *Settings) TimeoutOverflowMode(v string) {
+	s.configs["timeout_overflow_mode"] = v
+	s.dirty = true
+}
+
 // MinExecutionSpeed set min_execution_speed setting
 // Minimum number of execution rows per second.
 func (s *Settings) MinExecutionSpeed(v uint64) {
@@ -2071,3 +2197,41 @@ func (s *Settings) OutputFormatWriteStatistics(v bool) {
 	s.configs["output_format_write_statistics"] = v
 	s.dirty = true
 }
+
+// AsyncInsert set async_insert setting
+// If true, data from INSERT query is stored in queue and later flushed to table
+// in background.
+func (s *Settings) AsyncInsert(v bool) {
+	s.configs["async_insert"] = v
+	s.dirty = true
+}
+
+// WaitForAsyncInsert set wait_for_async_insert setting
+// If true wait for processing of asynchronous insertion
+func (s *Settings) WaitForAsyncInsert(v bool) {
+	s.configs["wait_for_async_insert"] = v
+	s.dirty = true
+}
+
+// WaitForAsyncInsertTimeout set wait_for_async_insert_timeout setting
+// Timeout for waiting for processing asynchronous insertion
+func (s *Settings) WaitForAsyncInsertTimeout(v time.Duration) {
+	s.configs["wait_for_async_insert_timeout"] = uint64(v.Seconds())
+	s.dirty = true
+}
+
+// AsyncInsertBusyTimeoutMs set async_insert_busy_timeout_ms setting
+// Maximum time to wait before dumping collected data per query since the first
+// data appeared
+func (s *Settings) AsyncInsertBusyTimeoutMs(v time.Duration) {
+	s.configs["async_insert_busy_timeout_ms"] = uint64(v.Milliseconds())
+	s.dirty = true
+}
+
+// AsyncInsertMaxDataSize set async_insert_max_data_size setting
+// Maximum size in bytes of unparsed data collected per query before being
+// inserted
+func (s *Settings) AsyncInsertMaxDataSize(v uint64) {
+	s.configs["async_insert_max_data_size"] = v
+	s.dirty = true
+}
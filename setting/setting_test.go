@@ -1633,6 +1633,19 @@ func TestSetting(t *testing.T) {
 		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
 	})
 
+	t.Run("timeout_overflow_mode", func(t *testing.T) {
+		setting := NewSettings()
+		setting.TimeoutOverflowMode("break")
+		writerExcept := readerwriter.NewWriter()
+		writerActual := readerwriter.NewWriter()
+		writerExcept.String("timeout_overflow_mode")
+		// flag
+		writerExcept.Uint8(0)
+		writerExcept.String("break")
+		setting.WriteTo(writerActual.Output(), true)
+		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
+	})
+
 	t.Run("min_execution_speed", func(t *testing.T) {
 		setting := NewSettings()
 		setting.MinExecutionSpeed(2)
@@ -3387,4 +3400,126 @@ func TestSetting(t *testing.T) {
 		setting.WriteTo(writerActual.Output(), true)
 		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
 	})
+
+	t.Run("important", func(t *testing.T) {
+		setting := NewSettings()
+		setting.MaxBlockSize(2)
+		setting.Important("max_block_size")
+		writerExcept := readerwriter.NewWriter()
+		writerActual := readerwriter.NewWriter()
+		writerExcept.String("max_block_size")
+		// flag
+		writerExcept.Uint8(0x01)
+		writerExcept.String("2")
+		setting.WriteTo(writerActual.Output(), true)
+		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		setting := NewSettings()
+		setting.Custom("custom_name", `it's \ value`)
+		writerExcept := readerwriter.NewWriter()
+		writerActual := readerwriter.NewWriter()
+		writerExcept.String("custom_name")
+		// flag
+		writerExcept.Uint8(0x02)
+		writerExcept.String(`'it\'s \\ value'`)
+		setting.WriteTo(writerActual.Output(), true)
+		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		setting := NewSettings()
+		setting.MaxBlockSize(2)
+		setting.Important("max_block_size")
+		writerExcept := readerwriter.NewWriter()
+		writerActual := readerwriter.NewWriter()
+		writerExcept.String("max_block_size")
+		writerExcept.Uvarint(2)
+		setting.WriteTo(writerActual.Output(), false)
+		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
+
+		// the format is changed for the same settings
+		writerExcept.Reset()
+		writerActual.Reset()
+		writerExcept.String("max_block_size")
+		// flag
+		writerExcept.Uint8(0x01)
+		writerExcept.String("2")
+		setting.WriteTo(writerActual.Output(), true)
+		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
+
+		for _, tt := range []struct {
+			set      func(s *Settings)
+			name     string
+			expected func(w *readerwriter.Writer)
+		}{
+			{func(s *Settings) { s.NetworkZstdCompressionLevel(-3) }, "network_zstd_compression_level",
+				func(w *readerwriter.Writer) { w.Varint(-3) }},
+			{func(s *Settings) { s.AsyncInsert(true) }, "async_insert",
+				func(w *readerwriter.Writer) { w.Uvarint(1) }},
+			{func(s *Settings) { s.InsertDeduplicationToken("token") }, "insert_deduplication_token",
+				func(w *readerwriter.Writer) { w.String("token") }},
+			{func(s *Settings) { s.AsyncInsertBusyTimeoutMs(durExample) }, "async_insert_busy_timeout_ms",
+				func(w *readerwriter.Writer) { w.Uvarint(4000) }},
+			{func(s *Settings) { s.FormatCsvDelimiter(';') }, "format_csv_delimiter",
+				func(w *readerwriter.Writer) { w.String(";") }},
+		} {
+			setting := NewSettings()
+			tt.set(setting)
+			writerExcept := readerwriter.NewWriter()
+			writerActual := readerwriter.NewWriter()
+			writerExcept.String(tt.name)
+			tt.expected(writerExcept)
+			setting.WriteTo(writerActual.Output(), false)
+			require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes(), tt.name)
+		}
+	})
+
+	t.Run("has_custom", func(t *testing.T) {
+		setting := NewSettings()
+		setting.MaxBlockSize(2)
+		setting.Important("max_block_size")
+		require.False(t, setting.HasCustom())
+		setting.Custom("custom_name", "value")
+		require.True(t, setting.HasCustom())
+	})
+
+	t.Run("get", func(t *testing.T) {
+		setting := NewSettings()
+		setting.AsyncInsert(true)
+		v, ok := setting.Get("async_insert")
+		require.True(t, ok)
+		require.Equal(t, true, v)
+		_, ok = setting.Get("wait_for_async_insert")
+		require.False(t, ok)
+	})
+
+	t.Run("async_insert_busy_timeout_ms", func(t *testing.T) {
+		setting := NewSettings()
+		setting.AsyncInsertBusyTimeoutMs(durExample)
+		writerExcept := readerwriter.NewWriter()
+		writerActual := readerwriter.NewWriter()
+		writerExcept.String("async_insert_busy_timeout_ms")
+		// flag
+		writerExcept.Uint8(0)
+		writerExcept.String("4000")
+		setting.WriteTo(writerActual.Output(), true)
+		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
+	})
+
+	t.Run("clone", func(t *testing.T) {
+		setting := NewSettings()
+		setting.Custom("custom_name", "value")
+		clone := setting.Clone()
+		writerExcept := readerwriter.NewWriter()
+		writerActual := readerwriter.NewWriter()
+		setting.WriteTo(writerExcept.Output(), true)
+		clone.WriteTo(writerActual.Output(), true)
+		require.Equal(t, writerExcept.Output().Bytes(), writerActual.Output().Bytes())
+
+		clone.InsertDeduplicationToken("token")
+		_, ok := setting.Get("insert_deduplication_token")
+		require.False(t, ok)
+	})
 }
@@ -0,0 +1,287 @@
+package chconn
+
+import (
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+)
+
+// lowCardinalityHasAdditionalKeys is the bit of the LowCardinality index serialization type that says the dictionary
+// keys are sent before the indexes.
+const lowCardinalityHasAdditionalKeys = 1 << 9
+
+// skipColumn discards the serialized data of a column with the ClickHouse type chType and numRows rows.
+// It is used to read past the blocks the caller is not interested in (e.g. when a query is canceled) without knowing the
+// columns in advance.
+func skipColumn(r *readerwriter.Reader, chType string, numRows int) error {
+	// zero rows is always represented as zero bytes, including the serialization prefix
+	if numRows == 0 {
+		return nil
+	}
+	if err := skipColumnPrefix(r, chType); err != nil {
+		return err
+	}
+	return skipColumnData(r, chType, numRows)
+}
+
+func skipColumnPrefix(r *readerwriter.Reader, chType string) error {
+	name, args := splitChType(chType)
+	switch name {
+	case "LowCardinality":
+		// KeysSerializationVersion
+		_, err := r.Uint64()
+		return err
+	case "Nullable", "Array":
+		return skipColumnPrefix(r, args[0])
+	case "Map", "Tuple", "Nested":
+		for _, arg := range args {
+			if err := skipColumnPrefix(r, tupleElementType(arg)); err != nil {
+				return err
+			}
+		}
+	case "SimpleAggregateFunction":
+		return skipColumnPrefix(r, args[len(args)-1])
+	case "Ring", "LineString", "Polygon", "MultiLineString", "MultiPolygon":
+		return skipColumnPrefix(r, geoTypes[name])
+	}
+	return nil
+}
+
+//nolint:gocyclo
+func skipColumnData(r *readerwriter.Reader, chType string, numRows int) error {
+	if size := fixedTypeSize(chType); size > 0 {
+		return skipBytes(r, int64(size*numRows))
+	}
+	name, args := splitChType(chType)
+	switch name {
+	case "String":
+		for i := 0; i < numRows; i++ {
+			strlen, err := r.Uvarint()
+			if err != nil {
+				return err
+			}
+			if err := skipBytes(r, int64(strlen)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "FixedString":
+		size, err := strconv.Atoi(args[0])
+		if err != nil {
+			return &unsupportedTypeError{chType: chType}
+		}
+		return skipBytes(r, int64(size*numRows))
+	case "Nullable":
+		if err := skipBytes(r, int64(numRows)); err != nil {
+			return err
+		}
+		return skipColumnData(r, args[0], numRows)
+	case "Array", "Map", "Nested":
+		totalRows, err := skipOffsets(r, numRows)
+		if err != nil {
+			return err
+		}
+		if name == "Array" {
+			return skipColumnData(r, args[0], totalRows)
+		}
+		return skipTupleData(r, args, totalRows)
+	case "Tuple":
+		return skipTupleData(r, args, numRows)
+	case "LowCardinality":
+		return skipLowCardinalityData(r, args[0], numRows)
+	case "SimpleAggregateFunction":
+		return skipColumnData(r, args[len(args)-1], numRows)
+	case "Ring", "LineString", "Polygon", "MultiLineString", "MultiPolygon":
+		return skipColumnData(r, geoTypes[name], numRows)
+	}
+	return &unsupportedTypeError{chType: chType}
+}
+
+func skipTupleData(r *readerwriter.Reader, elements []string, numRows int) error {
+	for _, element := range elements {
+		if err := skipColumnData(r, tupleElementType(element), numRows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipOffsets skips the offsets of an array column and returns the number of rows of the nested column.
+func skipOffsets(r *readerwriter.Reader, numRows int) (int, error) {
+	if err := skipBytes(r, int64((numRows-1)*8)); err != nil {
+		return 0, err
+	}
+	lastOffset, err := r.Uint64()
+	return int(lastOffset), err
+}
+
+func skipLowCardinalityData(r *readerwriter.Reader, chType string, numRows int) error {
+	// the dictionary never contains the nulls, they are stored as the index zero
+	if name, args := splitChType(chType); name == "Nullable" {
+		chType = args[0]
+	}
+	for numRows > 0 {
+		serializationType, err := r.Uint64()
+		if err != nil {
+			return err
+		}
+		if serializationType&lowCardinalityHasAdditionalKeys != 0 {
+			numKeys, err := r.Uint64()
+			if err != nil {
+				return err
+			}
+			if err := skipColumnData(r, chType, int(numKeys)); err != nil {
+				return err
+			}
+		}
+		numIndexes, err := r.Uint64()
+		if err != nil {
+			return err
+		}
+		indexSize := 1 << (serializationType & 0xf)
+		if err := skipBytes(r, int64(numIndexes)*int64(indexSize)); err != nil {
+			return err
+		}
+		numRows -= int(numIndexes)
+	}
+	return nil
+}
+
+func skipBytes(r *readerwriter.Reader, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := io.CopyN(ioutil.Discard, r, n)
+	return err
+}
+
+var geoTypes = map[string]string{
+	"Ring":            "Array(Point)",
+	"LineString":      "Array(Point)",
+	"Polygon":         "Array(Ring)",
+	"MultiLineString": "Array(LineString)",
+	"MultiPolygon":    "Array(Polygon)",
+}
+
+var fixedTypeSizes = map[string]int{
+	"UInt8":      1,
+	"Int8":       1,
+	"Bool":       1,
+	"Nothing":    1,
+	"UInt16":     2,
+	"Int16":      2,
+	"Date":       2,
+	"UInt32":     4,
+	"Int32":      4,
+	"Float32":    4,
+	"Date32":     4,
+	"DateTime":   4,
+	"IPv4":       4,
+	"Decimal32":  4,
+	"UInt64":     8,
+	"Int64":      8,
+	"Float64":    8,
+	"DateTime64": 8,
+	"Decimal64":  8,
+	"UInt128":    16,
+	"Int128":     16,
+	"UUID":       16,
+	"IPv6":       16,
+	"Decimal128": 16,
+	"UInt256":    32,
+	"Int256":     32,
+	"Decimal256": 32,
+	"Point":      16,
+}
+
+// fixedTypeSize returns the size of each row of the fixed size types or zero for the other types.
+func fixedTypeSize(chType string) int {
+	name, args := splitChType(chType)
+	switch {
+	case name == "Enum8":
+		return 1
+	case name == "Enum16":
+		return 2
+	case strings.HasPrefix(name, "Interval"):
+		return 8
+	case name == "Decimal" && len(args) == 2:
+		precision, err := strconv.Atoi(args[0])
+		if err != nil {
+			return 0
+		}
+		switch {
+		case precision <= 9:
+			return 4
+		case precision <= 18:
+			return 8
+		case precision <= 38:
+			return 16
+		}
+		return 32
+	}
+	return fixedTypeSizes[name]
+}
+
+// splitChType splits a ClickHouse type into its name and its top level arguments.
+// e.g. "Map(String, Array(UInt8))" returns "Map" and ["String", "Array(UInt8)"]
+func splitChType(chType string) (name string, args []string) {
+	chType = strings.TrimSpace(chType)
+	start := strings.IndexByte(chType, '(')
+	if start < 0 || chType[len(chType)-1] != ')' {
+		return chType, nil
+	}
+	name = chType[:start]
+	body := chType[start+1 : len(chType)-1]
+
+	var depth int
+	var quote byte
+	last := 0
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '`' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(body[last:i]))
+			last = i + 1
+		}
+	}
+	return name, append(args, strings.TrimSpace(body[last:]))
+}
+
+// tupleElementType returns the type of a tuple element which may be named (e.g. "a UInt8").
+func tupleElementType(element string) string {
+	var quote byte
+	for i := 0; i < len(element); i++ {
+		c := element[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '`' || c == '"':
+			quote = c
+		case c == '(' || c == '\'':
+			// the type name always comes before any parentheses or string literal
+			return element
+		case c == ' ':
+			return strings.TrimSpace(element[i+1:])
+		}
+	}
+	return element
+}
@@ -0,0 +1,102 @@
+package chconn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+)
+
+func TestSkipColumn(t *testing.T) {
+	t.Parallel()
+
+	str := "str"
+	colUint64 := column.NewUint64(false)
+	colNullableString := column.NewString(true)
+	colArraySub := column.NewUint8(false)
+	colArray := column.NewArray(colArraySub)
+	colMapKey := column.NewString(false)
+	colMapValue := column.NewUint64(false)
+	colMap := column.NewMap(colMapKey, colMapValue)
+	colLCDict := column.NewString(true)
+	colLC := column.NewLC(colLCDict)
+	colDecimal := column.NewDecimal64(4, false)
+	rows := 10
+	for i := 0; i < rows; i++ {
+		colUint64.Append(uint64(i))
+		if i%2 == 0 {
+			colNullableString.AppendStringP(nil)
+			colLCDict.AppendDictP(nil)
+		} else {
+			colNullableString.AppendStringP(&str)
+			colLCDict.AppendDict([]byte(str))
+		}
+		colArray.AppendLen(i)
+		for j := 0; j < i; j++ {
+			colArraySub.Append(uint8(j))
+		}
+		colMap.AppendLen(1)
+		colMapKey.AppendString(str)
+		colMapValue.Append(uint64(i))
+		colDecimal.Append(float64(i))
+	}
+
+	tests := []struct {
+		chType string
+		col    column.Column
+	}{
+		{"UInt64", colUint64},
+		{"Nullable(String)", colNullableString},
+		{"Array(UInt8)", colArray},
+		{"Map(String, UInt64)", colMap},
+		{"LowCardinality(Nullable(String))", colLC},
+		{"Decimal(18, 4)", colDecimal},
+	}
+	for _, tt := range tests {
+		w := readerwriter.NewWriter()
+		tt.col.HeaderWriter(w)
+		_, err := tt.col.WriteTo(w.Output())
+		require.NoError(t, err, tt.chType)
+		// the data after the column must be kept
+		w.Uint8(0xff)
+
+		r := readerwriter.NewReader(bytes.NewReader(w.Output().Bytes()))
+		require.NoError(t, skipColumn(r, tt.chType, rows), tt.chType)
+		b, err := r.ReadByte()
+		require.NoError(t, err, tt.chType)
+		assert.Equal(t, byte(0xff), b, tt.chType)
+	}
+
+	// named tuple
+	w := readerwriter.NewWriter()
+	w.Uint8(1)
+	w.String("a")
+	w.Uint8(0xff)
+	r := readerwriter.NewReader(bytes.NewReader(w.Output().Bytes()))
+	require.NoError(t, skipColumn(r, "Tuple(a UInt8, `b c` String)", 1))
+	b, err := r.ReadByte()
+	require.NoError(t, err)
+	assert.Equal(t, byte(0xff), b)
+
+	r = readerwriter.NewReader(bytes.NewReader(nil))
+	assert.EqualError(t, skipColumn(r, "Object('json')", 1), "unsupported column type: Object('json')")
+}
+
+func TestSplitChType(t *testing.T) {
+	t.Parallel()
+
+	name, args := splitChType("Map(String, Array(Tuple(a UInt8, b DateTime('Asia/Tehran'))))")
+	assert.Equal(t, "Map", name)
+	assert.Equal(t, []string{"String", "Array(Tuple(a UInt8, b DateTime('Asia/Tehran')))"}, args)
+
+	name, args = splitChType("Enum8('a,b' = 1, 'c)' = 2)")
+	assert.Equal(t, "Enum8", name)
+	assert.Equal(t, []string{"'a,b' = 1", "'c)' = 2"}, args)
+
+	name, args = splitChType("UInt8")
+	assert.Equal(t, "UInt8", name)
+	assert.Nil(t, args)
+}
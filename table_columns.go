@@ -0,0 +1,159 @@
+package chconn
+
+import (
+	"errors"
+	"strings"
+)
+
+// TableColumn is the description of a column of the table that the server sends for insert queries.
+type TableColumn struct {
+	Name string
+	Type string
+	// DefaultKind is DEFAULT, MATERIALIZED, ALIAS or EPHEMERAL. It is empty if the column has no default value
+	DefaultKind string
+	// DefaultExpression is the expression of the default value (e.g. now())
+	DefaultExpression string
+	Comment           string
+	// Codec is the compression codec of the column (e.g. CODEC(ZSTD(1)))
+	Codec string
+	TTL   string
+}
+
+// HasDefault returns true if the server can fill the column when it is omitted in the insert query
+func (c *TableColumn) HasDefault() bool {
+	return c.DefaultKind == "DEFAULT" || c.DefaultKind == "EPHEMERAL"
+}
+
+var errTableColumnsFormat = errors.New("invalid columns description")
+
+// parseTableColumns parses the columns description of the table.
+// e.g.
+// columns format version: 1
+// 2 columns:
+// `id` UInt64
+// `created` DateTime	DEFAULT	now()
+func parseTableColumns(description string) ([]TableColumn, error) {
+	lines := strings.Split(strings.TrimSuffix(description, "\n"), "\n")
+	if len(lines) < 2 || lines[0] != "columns format version: 1" || !strings.HasSuffix(lines[1], " columns:") {
+		return nil, errTableColumnsFormat
+	}
+	columns := make([]TableColumn, 0, len(lines)-2)
+	for _, line := range lines[2:] {
+		col, err := parseTableColumn(line)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+func parseTableColumn(line string) (TableColumn, error) {
+	var col TableColumn
+	if !strings.HasPrefix(line, "`") {
+		return col, errTableColumnsFormat
+	}
+	// the name is back quoted and the rest of the line is escaped, so the tabs only separate the fields
+	end := 1
+	for ; end < len(line) && line[end] != '`'; end++ {
+		if line[end] == '\\' {
+			end++
+		}
+	}
+	if end >= len(line) || !strings.HasPrefix(line[end+1:], " ") {
+		return col, errTableColumnsFormat
+	}
+	col.Name = unescapeString(line[1:end])
+
+	fields := strings.Split(line[end+2:], "\t")
+	col.Type = unescapeString(fields[0])
+	for i := 1; i < len(fields); i++ {
+		field := unescapeString(fields[i])
+		switch {
+		case field == "DEFAULT" || field == "MATERIALIZED" || field == "ALIAS" || field == "EPHEMERAL":
+			if i+1 >= len(fields) {
+				return col, errTableColumnsFormat
+			}
+			col.DefaultKind = field
+			i++
+			col.DefaultExpression = unescapeString(fields[i])
+		case strings.HasPrefix(field, "COMMENT "):
+			comment := strings.TrimPrefix(field, "COMMENT ")
+			if len(comment) < 2 || comment[0] != '\'' || comment[len(comment)-1] != '\'' {
+				return col, errTableColumnsFormat
+			}
+			col.Comment = unescapeString(comment[1 : len(comment)-1])
+		case strings.HasPrefix(field, "CODEC("):
+			col.Codec = field
+		case strings.HasPrefix(field, "TTL "):
+			col.TTL = strings.TrimPrefix(field, "TTL ")
+		}
+	}
+	return col, nil
+}
+
+// unescapeString reverses the backslash escaping of ClickHouse strings.
+func unescapeString(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '0':
+			b.WriteByte(0)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+var backQuoter = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+// backQuote quotes an identifier (e.g. a column name) for the query.
+func backQuote(name string) string {
+	return "`" + backQuoter.Replace(name) + "`"
+}
+
+// quote quotes the database and the name of the table for the query.
+func (t TableName) quote() string {
+	if t.Database == "" {
+		return backQuote(t.Table)
+	}
+	return backQuote(t.Database) + "." + backQuote(t.Table)
+}
+
+// readTableColumns reads the description of the columns of the table that the server sends before the insert block.
+func (ch *conn) readTableColumns() error {
+	// the name is always empty
+	if _, err := ch.reader.String(); err != nil {
+		return &readError{"table columns: read name", err}
+	}
+	description, err := ch.reader.String()
+	if err != nil {
+		return &readError{"table columns: read description", err}
+	}
+	columns, err := parseTableColumns(description)
+	if err != nil {
+		return &readError{"table columns: parse description", err}
+	}
+	ch.tableColumns = columns
+	return nil
+}
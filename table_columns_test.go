@@ -0,0 +1,108 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+)
+
+func TestParseTableColumns(t *testing.T) {
+	columns, err := parseTableColumns("columns format version: 1\n" +
+		"4 columns:\n" +
+		"`id` UInt64\n" +
+		"`created` DateTime\tDEFAULT\tnow()\tCOMMENT \\'it\\\\\\'s\\ttime\\'\n" +
+		"`name\\`s` LowCardinality(String)\tMATERIALIZED\tupper(\\'a\\')\tCODEC(ZSTD(1))\n" +
+		"`data` String\tTTL created + toIntervalDay(1)\n")
+	require.NoError(t, err)
+	assert.Equal(t, []TableColumn{
+		{Name: "id", Type: "UInt64"},
+		{
+			Name:              "created",
+			Type:              "DateTime",
+			DefaultKind:       "DEFAULT",
+			DefaultExpression: "now()",
+			Comment:           "it's\ttime",
+		},
+		{
+			Name:              "name`s",
+			Type:              "LowCardinality(String)",
+			DefaultKind:       "MATERIALIZED",
+			DefaultExpression: "upper('a')",
+			Codec:             "CODEC(ZSTD(1))",
+		},
+		{Name: "data", Type: "String", TTL: "created + toIntervalDay(1)"},
+	}, columns)
+	assert.False(t, columns[0].HasDefault())
+	assert.True(t, columns[1].HasDefault())
+	assert.False(t, columns[2].HasDefault())
+
+	_, err = parseTableColumns("2 columns:\n`id` UInt64\n")
+	assert.Equal(t, errTableColumnsFormat, err)
+	_, err = parseTableColumns("columns format version: 1\n1 columns:\nid UInt64\n")
+	assert.Equal(t, errTableColumnsFormat, err)
+}
+
+func TestInsertOmitDefaults(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+
+	_, err = conn.Exec(context.Background(), `DROP TABLE IF EXISTS `+backQuote("test_insert_omit.defaults"))
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), `CREATE TABLE `+backQuote("test_insert_omit.defaults")+` (
+		id UInt64,
+		name String DEFAULT 'unknown',
+		value UInt64 DEFAULT id * 2,
+		double UInt64 MATERIALIZED id * 2
+	) Engine=Memory`)
+	require.NoError(t, err)
+
+	stmt, err := conn.InsertOmitDefaults(context.Background(),
+		TableName{Table: "test_insert_omit.defaults"}, []string{"value"}, &QueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, stmt.TableColumns(), 4)
+	assert.Equal(t, "DEFAULT", stmt.TableColumns()[1].DefaultKind)
+	assert.Equal(t, "'unknown'", stmt.TableColumns()[1].DefaultExpression)
+	require.Len(t, stmt.GetBlock().Columns, 2)
+	assert.Equal(t, "id", stmt.GetBlock().Columns[0].Name)
+	assert.Equal(t, "value", stmt.GetBlock().Columns[1].Name)
+
+	colID := column.NewUint64(false)
+	colValue := column.NewUint64(false)
+	colID.Append(1)
+	colValue.Append(5)
+	require.NoError(t, stmt.Commit(context.Background(), colID, colValue))
+
+	selectStmt, err := conn.Select(context.Background(), `SELECT name, value FROM `+backQuote("test_insert_omit.defaults"))
+	require.NoError(t, err)
+	require.True(t, selectStmt.Next())
+	colName := column.NewString(false)
+	require.NoError(t, selectStmt.NextColumn(colName))
+	require.NoError(t, selectStmt.NextColumn(colValue))
+	var names []string
+	var values []uint64
+	colName.ReadAllString(&names)
+	colValue.ReadAll(&values)
+	assert.Equal(t, []string{"unknown"}, names)
+	assert.Equal(t, []uint64{5}, values)
+	for selectStmt.Next() {
+	}
+	require.NoError(t, selectStmt.Err())
+	selectStmt.Close()
+}
+
+func TestTableNameQuote(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "`events`", TableName{Table: "events"}.quote())
+	assert.Equal(t, "`db`.`select`", TableName{Database: "db", Table: "select"}.quote())
+	assert.Equal(t, "`a.b`", TableName{Table: "a.b"}.quote())
+	assert.Equal(t, "`a\\`; DROP TABLE b; --`", TableName{Table: "a`; DROP TABLE b; --"}.quote())
+}
@@ -0,0 +1,113 @@
+package chconn
+
+import (
+	"context"
+	"time"
+)
+
+// TableName is the name of a table and its database
+type TableName struct {
+	Database string
+	Table    string
+}
+
+// TableStatus is the replication status of a table on the server.
+type TableStatus struct {
+	// IsReplicated is true if the table is replicated (e.g. ReplicatedMergeTree)
+	IsReplicated bool
+	// AbsoluteDelay is how much the replica is behind the other replicas. It is zero if the table is not replicated
+	AbsoluteDelay time.Duration
+}
+
+// tablesStatus is the response of the TablesStatusRequest packet.
+type tablesStatus map[TableName]TableStatus
+
+func (t tablesStatus) read(ch *conn) error {
+	size, err := ch.reader.Uvarint()
+	if err != nil {
+		return &readError{"tables status: read size", err}
+	}
+	for i := uint64(0); i < size; i++ {
+		var (
+			name   TableName
+			status TableStatus
+		)
+		if name.Database, err = ch.reader.String(); err != nil {
+			return &readError{"tables status: read database", err}
+		}
+		if name.Table, err = ch.reader.String(); err != nil {
+			return &readError{"tables status: read table", err}
+		}
+		if status.IsReplicated, err = ch.reader.Bool(); err != nil {
+			return &readError{"tables status: read is replicated", err}
+		}
+		if status.IsReplicated {
+			delay, err := ch.reader.Uvarint()
+			if err != nil {
+				return &readError{"tables status: read absolute delay", err}
+			}
+			status.AbsoluteDelay = time.Duration(delay) * time.Second
+		}
+		t[name] = status
+	}
+	return nil
+}
+
+// TablesStatus get the replication status of the tables (e.g. the delay of the replica).
+// The tables that do not exist on the server are not in the result
+func (ch *conn) TablesStatus(ctx context.Context, tables []TableName) (map[TableName]TableStatus, error) {
+	if ch.serverInfo.Revision < dbmsMinRevisionWithTablesStatus {
+		return nil, ErrTablesStatusNotSupported
+	}
+	err := ch.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer ch.unlock()
+
+	ch.watch(ctx)
+	defer ch.contextWatcher.Unwatch()
+	var hasError bool
+	defer func() {
+		if hasError {
+			ch.Close(context.Background())
+		}
+	}()
+
+	ch.writeMu.Lock()
+	if ch.canceled {
+		ch.writeMu.Unlock()
+		return nil, ctx.Err()
+	}
+	ch.writer.Uvarint(clientTablesStatusRequest)
+	ch.writer.Uvarint(uint64(len(tables)))
+	for _, table := range tables {
+		ch.writer.String(table.Database)
+		ch.writer.String(table.Table)
+	}
+	_, err = ch.writer.WriteTo(ch.writerto)
+	ch.writeMu.Unlock()
+	if err != nil {
+		err = &writeError{"tables status: write request", err}
+		if ch.isCanceled() {
+			return nil, ch.finishCanceled(ctx, nil, err)
+		}
+		hasError = true
+		return nil, err
+	}
+
+	res, err := ch.reciveAndProccessData(emptyOnProgress)
+	if ch.isCanceled() {
+		return nil, ch.finishCanceled(ctx, res, err)
+	}
+	if err != nil {
+		hasError = needsClose(err)
+		return nil, err
+	}
+	status, ok := res.(tablesStatus)
+	if !ok {
+		hasError = true
+		return nil, &unexpectedPacket{expected: "serverTablesStatusResponse", actual: res}
+	}
+	return status, nil
+}
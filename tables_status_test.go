@@ -0,0 +1,69 @@
+package chconn
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTablesStatus(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(context.Background(), `DROP TABLE IF EXISTS clickhouse_test_tables_status`)
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), `CREATE TABLE clickhouse_test_tables_status (
+				id UInt64
+			) Engine=MergeTree ORDER BY id`)
+	require.NoError(t, err)
+
+	table := TableName{Database: "default", Table: "clickhouse_test_tables_status"}
+	notExist := TableName{Database: "default", Table: "clickhouse_test_tables_status_not_exist"}
+	status, err := conn.TablesStatus(context.Background(), []TableName{table, notExist})
+	require.NoError(t, err)
+	require.Equal(t, map[TableName]TableStatus{
+		table: {IsReplicated: false},
+	}, status)
+
+	// the connection is usable after the request
+	require.NoError(t, conn.Ping(context.Background()))
+}
+
+func TestTablesStatusError(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	config, err := ParseConfig(connString)
+	require.NoError(t, err)
+
+	config.WriterFunc = func(w io.Writer) io.Writer {
+		return &writerErrorHelper{
+			err:         errors.New("timeout"),
+			w:           w,
+			numberValid: 1,
+		}
+	}
+	c, err := ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	_, err = c.TablesStatus(context.Background(), []TableName{{Database: "system", Table: "numbers"}})
+	require.EqualError(t, err, "tables status: write request (timeout)")
+	require.True(t, c.IsClosed())
+
+	config.WriterFunc = nil
+	c, err = ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	c.(*conn).serverInfo.Revision = dbmsMinRevisionWithTablesStatus - 1
+	_, err = c.TablesStatus(context.Background(), nil)
+	require.Equal(t, ErrTablesStatusNotSupported, err)
+	c.Close(context.Background())
+}
@@ -0,0 +1,26 @@
+package chconn
+
+import (
+	"net"
+	"syscall"
+	"time"
+)
+
+// tcpUserTimeout is the TCP_USER_TIMEOUT socket option of linux. It is not defined in the syscall package
+const tcpUserTimeout = 0x12
+
+// setTCPUserTimeout sets the TCP_USER_TIMEOUT socket option in milliseconds.
+func setTCPUserTimeout(conn *net.TCPConn, timeout time.Duration) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errSetsockopt error
+	err = rawConn.Control(func(fd uintptr) {
+		errSetsockopt = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, tcpUserTimeout, int(timeout/time.Millisecond))
+	})
+	if err != nil {
+		return err
+	}
+	return errSetsockopt
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package chconn
+
+import (
+	"net"
+	"time"
+)
+
+// setTCPUserTimeout does nothing, because TCP_USER_TIMEOUT is only supported on linux.
+func setTCPUserTimeout(conn *net.TCPConn, timeout time.Duration) error {
+	return nil
+}
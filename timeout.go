@@ -0,0 +1,74 @@
+package chconn
+
+import (
+	"io"
+	"net"
+	"time"
+)
+
+// timeoutReader sets the read deadline of the connection before each read, so Config.ReadTimeout is the max time
+// that a read can be blocked.
+type timeoutReader struct {
+	ch *conn
+	r  io.Reader
+}
+
+func (r *timeoutReader) Read(p []byte) (int, error) {
+	r.ch.extendDeadline(r.ch.conn.SetReadDeadline, r.ch.config.ReadTimeout)
+	return r.r.Read(p)
+}
+
+// timeoutWriter sets the write deadline of the connection before each write, so Config.WriteTimeout is the max time
+// that a write can be blocked.
+type timeoutWriter struct {
+	ch *conn
+	w  io.Writer
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.ch.extendDeadline(w.ch.conn.SetWriteDeadline, w.ch.config.WriteTimeout)
+	return w.w.Write(p)
+}
+
+// extendDeadline sets the deadline to timeout from now, unless the deadline is fixed to interrupt the connection.
+func (ch *conn) extendDeadline(setDeadline func(time.Time) error, timeout time.Duration) {
+	ch.deadlineMu.Lock()
+	defer ch.deadlineMu.Unlock()
+	if !ch.fixedDeadline {
+		setDeadline(time.Now().Add(timeout)) //nolint:errcheck //no need
+	}
+}
+
+// setFixedDeadline sets the deadline of the connection (e.g. for the cancellation). ReadTimeout and WriteTimeout don't
+// change it until it is reset with the zero time.
+func (ch *conn) setFixedDeadline(t time.Time) {
+	ch.deadlineMu.Lock()
+	defer ch.deadlineMu.Unlock()
+	ch.fixedDeadline = !t.IsZero()
+	ch.conn.SetDeadline(t) //nolint:errcheck //no need
+}
+
+// setTCPOptions sets the keep-alive and the user timeout of the TCP connection. The connections of a custom DialFunc
+// that are not *net.TCPConn are not changed.
+func setTCPOptions(conn net.Conn, config *Config) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if config.KeepAlive == 0 {
+		if err := tcpConn.SetKeepAlive(false); err != nil {
+			return err
+		}
+	} else {
+		if err := tcpConn.SetKeepAlive(true); err != nil {
+			return err
+		}
+		if err := tcpConn.SetKeepAlivePeriod(config.KeepAlive); err != nil {
+			return err
+		}
+	}
+	if config.TCPUserTimeout > 0 {
+		return setTCPUserTimeout(tcpConn, config.TCPUserTimeout)
+	}
+	return nil
+}
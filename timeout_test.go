@@ -0,0 +1,63 @@
+package chconn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTimeout(t *testing.T) {
+	t.Parallel()
+
+	// the server accepts the connection and never answers the hello packet
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		time.Sleep(5 * time.Second)
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	config, err := ParseConfig("host=" + host + " port=" + port)
+	require.NoError(t, err)
+	config.ReadTimeout = 100 * time.Millisecond
+	config.TCPUserTimeout = time.Second
+
+	start := time.Now()
+	_, err = ConnectConfig(context.Background(), config)
+	require.Error(t, err)
+	var netErr net.Error
+	require.True(t, errors.As(err, &netErr))
+	require.True(t, netErr.Timeout())
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
+func TestSetTCPOptions(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, setTCPOptions(conn, &Config{KeepAlive: time.Minute, TCPUserTimeout: time.Second}))
+	require.NoError(t, setTCPOptions(conn, &Config{}))
+	// the connections that are not *net.TCPConn are not changed
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	require.NoError(t, setTCPOptions(client, &Config{KeepAlive: time.Minute, TCPUserTimeout: time.Second}))
+}
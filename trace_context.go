@@ -0,0 +1,66 @@
+package chconn
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// TraceContext is the W3C trace context (https://www.w3.org/TR/trace-context/) of a query.
+// The server links the spans of the query in system.opentelemetry_span_log to the span of the trace context.
+type TraceContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	TraceState string
+	TraceFlags uint8
+}
+
+// ErrInvalidTraceParent when the traceparent header is not valid
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+// ParseTraceParent parses the traceparent header (e.g. 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01)
+// and the tracestate header of the W3C trace context.
+func ParseTraceParent(traceParent, traceState string) (*TraceContext, error) {
+	parts := strings.Split(traceParent, "-")
+	// the future versions can have more fields
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return nil, ErrInvalidTraceParent
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, ErrInvalidTraceParent
+	}
+	tc := &TraceContext{
+		TraceState: traceState,
+	}
+	if _, err := hex.Decode(tc.TraceID[:], []byte(parts[1])); err != nil {
+		return nil, ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(tc.SpanID[:], []byte(parts[2])); err != nil {
+		return nil, ErrInvalidTraceParent
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return nil, ErrInvalidTraceParent
+	}
+	tc.TraceFlags = flags[0]
+	if tc.TraceID == [16]byte{} || tc.SpanID == [8]byte{} {
+		return nil, ErrInvalidTraceParent
+	}
+	return tc, nil
+}
+
+func (tc *TraceContext) write(ch *conn) {
+	if tc == nil || tc.TraceID == [16]byte{} {
+		// Don't have OpenTelemetry header.
+		ch.writer.Uint8(0)
+		return
+	}
+	ch.writer.Uint8(1)
+	// the server reads the trace id as UUID (the high 64 bits are first)
+	ch.writer.Uint64(binary.BigEndian.Uint64(tc.TraceID[:8]))
+	ch.writer.Uint64(binary.BigEndian.Uint64(tc.TraceID[8:]))
+	ch.writer.Uint64(binary.BigEndian.Uint64(tc.SpanID[:]))
+	ch.writer.String(tc.TraceState)
+	ch.writer.Uint8(tc.TraceFlags)
+}
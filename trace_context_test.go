@@ -0,0 +1,97 @@
+package chconn
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vahid-sohrabloo/chconn/column"
+	"github.com/vahid-sohrabloo/chconn/internal/readerwriter"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tc, err := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "congo=t61rcWkgMzE")
+	require.NoError(t, err)
+	assert.Equal(t, &TraceContext{
+		TraceID: [16]byte{
+			0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c,
+		},
+		SpanID:     [8]byte{0xb7, 0xad, 0x6b, 0x71, 0x69, 0x20, 0x33, 0x31},
+		TraceState: "congo=t61rcWkgMzE",
+		TraceFlags: 1,
+	}, tc)
+
+	// the future versions can have more fields
+	_, err = ParseTraceParent("01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra", "")
+	require.NoError(t, err)
+
+	for _, traceParent := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319x-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b716920333x-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-0x",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+	} {
+		_, err = ParseTraceParent(traceParent, "")
+		assert.Equal(t, ErrInvalidTraceParent, err, traceParent)
+	}
+}
+
+func TestTraceContextWrite(t *testing.T) {
+	ch := &conn{writer: readerwriter.NewWriter()}
+	var tc *TraceContext
+	tc.write(ch)
+	assert.Equal(t, []byte{0}, ch.writer.Output().Bytes())
+
+	ch = &conn{writer: readerwriter.NewWriter()}
+	tc, err := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "a=b")
+	require.NoError(t, err)
+	tc.write(ch)
+	assert.Equal(t, []byte{
+		1,
+		0xdd, 0x43, 0xcd, 0x16, 0x19, 0x65, 0xf7, 0x0a,
+		0x9c, 0x31, 0x80, 0x1c, 0x21, 0xeb, 0x48, 0x84,
+		0x31, 0x33, 0x20, 0x69, 0x71, 0x6b, 0xad, 0xb7,
+		3, 'a', '=', 'b',
+		1,
+	}, ch.writer.Output().Bytes())
+}
+
+func TestTraceContext(t *testing.T) {
+	t.Parallel()
+
+	connString := os.Getenv("CHX_TEST_TCP_CONN_STRING")
+
+	conn, err := Connect(context.Background(), connString)
+	require.NoError(t, err)
+
+	tc, err := ParseTraceParent("00-1af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "")
+	require.NoError(t, err)
+	_, err = conn.ExecWithOption(context.Background(), `SELECT 1`, &QueryOptions{
+		TraceContext: tc,
+	})
+	require.NoError(t, err)
+	_, err = conn.Exec(context.Background(), `SYSTEM FLUSH LOGS`)
+	require.NoError(t, err)
+
+	stmt, err := conn.Select(context.Background(), `SELECT count() FROM system.opentelemetry_span_log
+		WHERE trace_id = toUUID('1af76519-16cd-43dd-8448-eb211c80319c')`)
+	require.NoError(t, err)
+	col := column.NewUint64(false)
+	var count []uint64
+	for stmt.Next() {
+		require.NoError(t, stmt.NextColumn(col))
+		col.ReadAll(&count)
+	}
+	require.NoError(t, stmt.Err())
+	stmt.Close()
+	require.Len(t, count, 1)
+	assert.NotZero(t, count[0])
+}